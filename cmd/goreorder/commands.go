@@ -10,7 +10,6 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 
 	logger "github.com/metal3d/goreorder/log"
 	"github.com/metal3d/goreorder/ordering"
@@ -20,6 +19,7 @@ var (
 	version                     = "master"  // changed at compilation time
 	defaultOutpout    io.Writer = os.Stdout // default output is stdout
 	defaultErrOutpout io.Writer = os.Stderr // default error output is stderr
+	stdinInput        io.Reader = os.Stdin  // input read with the "-" argument
 )
 
 func buildCompletionCommand() *cobra.Command {
@@ -70,6 +70,88 @@ func buildCompletionCommand() *cobra.Command {
 	return completionCmd
 }
 
+func buildHookCommand() *cobra.Command {
+	hookCommand := &cobra.Command{
+		Use:   "hook",
+		Short: "Manage the git pre-commit hook that checks the order of the staged Go files",
+		// the configuration file is read by the hook, when it runs
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+	hookCommand.AddCommand(&cobra.Command{
+		Use:   "install",
+		Short: "Install the pre-commit hook, an existing hook is kept and the check is added to it",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hook, err := installHook()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(defaultErrOutpout, "Hook installed in %s\n", hook)
+			return nil
+		},
+	})
+	hookCommand.AddCommand(&cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the check from the pre-commit hook, and the hook if nothing else remains",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hook, err := uninstallHook()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(defaultErrOutpout, "Hook removed from %s\n", hook)
+			return nil
+		},
+	})
+	return hookCommand
+}
+
+func buildInitCommand() *cobra.Command {
+	force := false
+	dryRun := false
+	initCmd := &cobra.Command{
+		Use:   "init [directory]",
+		Short: "Create a .goreorder file with the configuration that fits the current ordering style of the sources",
+		Long: `Analyze the Go files of the directory (recursively) to find the order of elements
+and options that would change the fewest lines, then write the .goreorder file.`,
+		Args: cobra.MaximumNArgs(1),
+		// the configuration file is not needed, we are about to create it
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			directory := "."
+			if len(args) > 0 {
+				directory = args[0]
+			}
+			config, err := inferConfig(directory, defaultErrOutpout)
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				return printConfigFile(config)
+			}
+			filename := filepath.Join(directory, ".goreorder")
+			if err := writeConfigFile(filename, config, force); err != nil {
+				return err
+			}
+			fmt.Fprintf(defaultErrOutpout, "Configuration written to %s\n", filename)
+			return nil
+		},
+	}
+	initCmd.Flags().BoolVar(
+		&force,
+		"force", force,
+		"Overwrite the configuration file if it exists")
+	initCmd.Flags().BoolVar(
+		&dryRun,
+		"dry-run", dryRun,
+		"Print the configuration instead of writing the file")
+	return initCmd
+}
+
 func buildMainCommand() *cobra.Command {
 	const usage = `%[1]s reorders the types, methods... in a Go
 source file. By default, it will print the result to stdout. To allow %[1]s
@@ -77,16 +159,22 @@ to write to the file, use the -write flag.`
 	var examples = []string{
 		"$ %[1]s reorder --write --reorder-types --format gofmt file.go",
 		"$ %[1]s reorder --diff ./mypackage",
-		"$ cat file.go | %[1]s reorder",
+		"$ %[1]s reorder --write ./...",
+		"$ cat file.go | %[1]s reorder --stdin-filename file.go -",
 	}
 
 	cmd := cobra.Command{
-		Use:     "goreorder [flags] [file.go|directory|stdin]",
+		Use:     "goreorder [flags] [file.go|directory|./...|-]...",
 		Short:   "goreorder reorders the vars, const, types... in a Go source file.",
 		Example: fmt.Sprintf(strings.Join(examples, "\n"), filepath.Base(os.Args[0])),
 		Long:    fmt.Sprintf(usage, filepath.Base(os.Args[0])),
 		Version: version,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// the configuration of the directory of the source read from stdin, that file
+			// may not exist
+			if f := cmd.Flags().Lookup("stdin-filename"); f != nil && f.Value.String() != "" {
+				args = append(args, filepath.Dir(f.Value.String()))
+			}
 			return initializeViper(cmd, args...)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -98,63 +186,84 @@ to write to the file, use the -write flag.`
 	cmd.SetOut(defaultOutpout)
 	cmd.SetErr(defaultErrOutpout)
 
-	config := &ReorderConfig{
+	config := defaultConfig()
+	reorderCommand := buildReorderCommand(config)
+	cmd.AddCommand(reorderCommand)
+	cmd.AddCommand(buildPrintConfigCommand(config, reorderCommand))
+	cmd.AddCommand(buildConfigCommand(reorderCommand))
+	cmd.AddCommand(buildInitCommand())
+	cmd.AddCommand(buildCompletionCommand())
+	cmd.AddCommand(buildHookCommand())
+	return &cmd
+}
+
+// defaultConfig returns the configuration used when no value is given.
+func defaultConfig() *ReorderConfig {
+	return &ReorderConfig{
 		FormatToolName: "gofmt",
 		Write:          false,
 		Verbose:        false,
 		ReorderTypes:   false,
 		MakeDiff:       false,
+		Assertions:     ordering.AssertionsInVars,
+		AlignArch:      ordering.DefaultAlignArch,
+		BannerTemplate: ordering.DefaultBannerTemplate,
+		Spacing:        ordering.SpacingPreserve,
 	}
-	reorderCommand := buildReorderCommand(config)
-	cmd.AddCommand(reorderCommand)
-	cmd.AddCommand(buildPrintConfigCommand(config, reorderCommand))
-	cmd.AddCommand(buildCompletionCommand())
-	return &cmd
+}
+
+func buildConfigCommand(reorderCommand *cobra.Command) *cobra.Command {
+	configCommand := &cobra.Command{
+		Use:   "config",
+		Short: "Configuration file helpers",
+		// the configuration file is not needed (and may be invalid) here
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+	configCommand.AddCommand(&cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema of the configuration file, to be used by editors for validation and completion",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printConfigSchema(reorderCommand.Flags())
+		},
+	})
+	return configCommand
 }
 
 func buildPrintConfigCommand(config *ReorderConfig, reorderCommand *cobra.Command) *cobra.Command {
-	return &cobra.Command{
+	explain := false
+	printConfigCommand := &cobra.Command{
 		Use:   "print-config",
 		Short: "Print the configuration",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			initializeViper(reorderCommand)
-			bindFlags(reorderCommand, viper.GetViper())
-			printConfigFile(config)
-			return nil
+			if err := initializeViper(reorderCommand); err != nil {
+				return err
+			}
+			if explain {
+				return explainConfig(defaultOutpout, config, reorderCommand.Flags())
+			}
+			return printConfigFile(config)
 		},
 	}
+	printConfigCommand.Flags().BoolVar(
+		&explain,
+		"explain", explain,
+		"Show where each value comes from (default, profile, configuration file, environment or flag)")
+	return printConfigCommand
 }
 
 func buildReorderCommand(config *ReorderConfig) *cobra.Command {
 	reoderCommand := &cobra.Command{
-		Use:   "reorder [flags] [file.go|directory|stdin]",
+		Use:   "reorder [flags] [file.go|directory|./...|-]...",
 		Short: "Reorder vars, consts, stucts/types/interaces, methods/functions and constructors in a Go source file.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			stat, _ := os.Stdin.Stat()
-			if len(args) == 0 && (stat.Mode()&os.ModeCharDevice) != 0 {
-				return errors.New("you should provide a file or a directory or stream content to stdin")
+			if len(args) == 0 && config.FilesFrom == "" && config.ChangedSince == "" && !config.Staged {
+				return errors.New("you should provide a file, a directory, - to read the source from stdin, --files-from, --changed-since or --staged")
 			}
 
-			// validate order flags
-			validOrder := config.DefOrder
-			validOrder = append(validOrder, []string{"main", "init"}...)
-			if len(config.DefOrder) > 0 {
-				for _, v := range config.DefOrder {
-					found := false
-					for _, w := range validOrder {
-						if v == w {
-							found = true
-							break
-						}
-					}
-					if !found {
-						return fmt.Errorf("invalid order name %v, valid order name are %v", v, ordering.DefaultOrder)
-					}
-				}
-			}
-			// only allow gofmt or goimports
-			if config.FormatToolName != "gofmt" && config.FormatToolName != "goimports" {
-				return fmt.Errorf("only gofmt or goimports are allowed")
+			if err := validateConfig(config); err != nil {
+				return err
 			}
 
 			// check if the executable exists
@@ -162,10 +271,21 @@ func buildReorderCommand(config *ReorderConfig) *cobra.Command {
 				return fmt.Errorf("The executable '" + config.FormatToolName + "' does not exist")
 			}
 			logger.SetVerbose(config.Verbose)
-			return reorder(config, args...)
+
+			// the errors of the files are printed by reorder
+			cmd.SilenceUsage = true
+			err := reorder(config, args...)
+			var exit *exitError
+			cmd.SilenceErrors = errors.As(err, &exit)
+			return err
 		},
 	}
 
+	reoderCommand.Flags().StringVar(
+		&config.Profile,
+		"profile", config.Profile,
+		"Named preset of options, each option can be overridden by the configuration or by a flag.\n"+
+			"- Available profiles are: "+strings.Join(profileNames(), ", "))
 	reoderCommand.Flags().StringVarP(
 		&config.FormatToolName,
 		"format", "f", config.FormatToolName,
@@ -186,6 +306,69 @@ func buildReorderCommand(config *ReorderConfig) *cobra.Command {
 		&config.MakeDiff,
 		"diff", "d", config.MakeDiff,
 		"Print diff/patch format instead of rewriting the file")
+	reoderCommand.Flags().BoolVar(
+		&config.Check,
+		"check", config.Check,
+		"Do not write nor print the sources, list the files that need to be reordered and exit with code 1 if there are some")
+	reoderCommand.Flags().StringVar(
+		&config.StdinFilename,
+		"stdin-filename", config.StdinFilename,
+		"Path of the source read from stdin (with the - argument), used to find the configuration, the package interfaces and in the messages (default \""+defaultStdinFilename+"\")")
+	reoderCommand.Flags().StringVar(
+		&config.FilesFrom,
+		"files-from", config.FilesFrom,
+		"Read the files to process from a file, or from stdin with -, one path per line (e.g. the output of git diff --name-only). "+
+			"The files that are not Go sources are ignored")
+	reoderCommand.Flags().BoolVarP(
+		&config.NullSeparated,
+		"null", "0", config.NullSeparated,
+		"The paths of --files-from are separated by NUL characters (e.g. the output of git ls-files -z)")
+	reoderCommand.Flags().StringVar(
+		&config.ChangedSince,
+		"changed-since", config.ChangedSince,
+		"Process the Go files that changed since the git revision (committed or not), and the untracked ones")
+	reoderCommand.Flags().BoolVar(
+		&config.Staged,
+		"staged", config.Staged,
+		"Process the index version of the Go files staged in git. With --write, the result is staged, and written to "+
+			"the working tree if the file has no unstaged changes")
+	reoderCommand.Flags().BoolVar(
+		&config.Associate,
+		"associate", config.Associate,
+		"Place the consts and vars of a type right after it, before the constructors and methods (as go doc)")
+	reoderCommand.Flags().StringVar(
+		&config.Assertions,
+		"assertions", config.Assertions,
+		`Placement of the interface assertions (var _ I = (*T)(nil)):
+- var: in the var section, as other vars
+- after-type: right after the declaration of T
+- before-methods: after the constructors of T, before its methods`)
+	reoderCommand.Flags().BoolVar(
+		&config.Stepdown,
+		"stepdown", config.Stepdown,
+		"Order functions and methods so that each one is followed by the ones it calls, starting from the exported ones")
+	reoderCommand.Flags().BoolVar(
+		&config.ColocateHelpers,
+		"colocate-helpers", config.ColocateHelpers,
+		"Place the unexported functions only used by one type, or taking it as first parameter, after its methods")
+	reoderCommand.Flags().BoolVar(
+		&config.GroupByInterface,
+		"group-by-interface", config.GroupByInterface,
+		"Group the methods of a type by the interface they satisfy (declared in the package or well-known, as io.Reader), in the interface order")
+	reoderCommand.Flags().BoolVar(
+		&config.Banners,
+		"banners", config.Banners,
+		"Insert a banner comment before each group of declarations (e.g. // Types), the banners are replaced on each run")
+	reoderCommand.Flags().StringVar(
+		&config.BannerTemplate,
+		"banner-template", config.BannerTemplate,
+		"Template of the banners (text/template), it receives the Kind, Pattern and Title of the group and must render line comments")
+	reoderCommand.Flags().StringVar(
+		&config.Spacing,
+		"spacing", config.Spacing,
+		`Blank lines between the moved declarations:
+- preserve: as in the source
+- one: exactly one blank line`)
 	reoderCommand.Flags().StringSliceVarP(
 		&config.DefOrder,
 		"order", "o", config.DefOrder,
@@ -194,7 +377,48 @@ be placed in the default order after those you have specified.
 There are two specific cases: main and init - if they are not specified in the list, 
 then they are considered to be functions and will be ordered as such. If you do specify
 them, then they will be positioned in the source code in the place you have specified.
-- Allowed values are: main, init, `+strings.Join(ordering.DefaultOrder, ", ")+`
+Types can be split by kind with struct, alias, functype (func types) and basic (named basic
+types), the types of a kind that is not in the list are placed with "type".
+An entry can also be a kind with a name pattern (glob or /regexp/), e.g. type:*Error or
+func:/^Must/, the matching declarations are placed there instead of in their kind.
+- Allowed values are: `+strings.Join(allowedOrders(), ", ")+`
 - Default order is: `+strings.Join(ordering.DefaultOrder, ","))
+	reoderCommand.Flags().StringSliceVar(
+		&config.MethodOrder,
+		"method-order", config.MethodOrder,
+		`Keys used to sort the methods of a type, applied in the given order:
+- exported: exported methods first
+- receiver: value receivers before pointer receivers
+- wellknown: well-known methods last, in this order: `+strings.Join(ordering.WellKnownMethods, ", ")+`
+- name: alphabetical order
+- Default is: `+ordering.MethodName)
+	reoderCommand.Flags().BoolVar(
+		&config.PairAccessors,
+		"pair-accessors", config.PairAccessors,
+		"Keep getters and setters together (Name and SetName, IsEnabled and SetEnabled), getter first, sorted by property name")
+	reoderCommand.Flags().BoolVar(
+		&config.SortStructFields,
+		"sort-struct-fields", config.SortStructFields,
+		"Sort the fields of the structs in each group of lines, embedded fields first (structs used in unkeyed literals are kept as is)")
+	reoderCommand.Flags().BoolVar(
+		&config.SortInterfaceMethods,
+		"sort-interface-methods", config.SortInterfaceMethods,
+		"Sort the methods of the interfaces in each group of lines, embedded interfaces first")
+	reoderCommand.Flags().BoolVar(
+		&config.AlignStructs,
+		"align-structs", config.AlignStructs,
+		"Reorder the fields of the structs to minimize the padding, the structs annotated with "+ordering.NoAlignDirective+
+			", using cgo or binary encoding tags are kept as is")
+	reoderCommand.Flags().StringVar(
+		&config.AlignArch,
+		"align-arch", config.AlignArch,
+		"Architecture (GOARCH) used to compute the sizes of the fields with --align-structs\n"+
+			"- Allowed values are: "+strings.Join(ordering.AlignArchs, ", "))
+	reoderCommand.Flags().Var(
+		(*priorityValue)(&config.Priority),
+		"priority",
+		`Names to place first, in the given order, the others are sorted as usual. The format is
+kind=Name1,Name2 and the flag can be repeated for each kind.
+- Allowed kinds are: `+strings.Join(ordering.PriorityKinds, ", "))
 	return reoderCommand
 }
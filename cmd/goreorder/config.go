@@ -2,8 +2,10 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -12,6 +14,47 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	envPrefix        = "GOREORDER"
+	sourceAnnotation = "goreorder_source" // flag annotation that contains where the value comes from
+)
+
+// priorityValue is the flag value of the priority lists, "kind=Name1,Name2". The flag can be
+// repeated, once per kind.
+type priorityValue map[string][]string
+
+// Set adds the priority list of a kind.
+func (p *priorityValue) Set(value string) error {
+	kind, names, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("invalid priority %q, the format is kind=Name1,Name2", value)
+	}
+	if *p == nil {
+		*p = priorityValue{}
+	}
+	(*p)[strings.TrimSpace(kind)] = splitList(names)
+	return nil
+}
+
+// String returns the priority lists as "kind=Name1,Name2;kind=Name3".
+func (p *priorityValue) String() string {
+	kinds := make([]string, 0, len(*p))
+	for kind := range *p {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	values := make([]string, len(kinds))
+	for i, kind := range kinds {
+		values[i] = kind + "=" + strings.Join((*p)[kind], ",")
+	}
+	return strings.Join(values, ";")
+}
+
+// Type returns the flag type name.
+func (p *priorityValue) Type() string {
+	return "priority"
+}
+
 func initializeViper(c *cobra.Command, args ...string) error {
 	v := viper.New()
 	v.SetConfigName(".goreorder")
@@ -33,34 +76,132 @@ func initializeViper(c *cobra.Command, args ...string) error {
 
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			// try to give a more precise error with the position in the file
+			if file := v.ConfigFileUsed(); file != "" {
+				if verr := validateConfigFile(file, buildConfigSchema(nil)); verr != nil {
+					return verr
+				}
+			}
+			return err
+		}
+	}
+	if file := v.ConfigFileUsed(); file != "" {
+		if err := validateConfigFile(file, buildConfigSchema(nil)); err != nil {
 			return err
 		}
 	}
-	v.SetEnvPrefix("GOREORDER")
+	v.SetEnvPrefix(envPrefix)
+	// GOREORDER_REORDER_TYPES for "reorder-types"
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	v.AutomaticEnv()
+
+	// the profile gives default values to the other keys
+	if err := applyProfile(v, currentProfile(c, v)); err != nil {
+		return err
+	}
+
 	bindFlags(c, v)
 	return nil
 }
 
 func bindFlags(cmd *cobra.Command, v *viper.Viper) {
+	profile := currentProfile(cmd, v)
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
 		name := f.Name
 		if !f.Changed && v.IsSet(name) {
 			// ensure that the value is with the correct type
 			switch f.Value.Type() {
 			case "stringSlice":
-				cmd.Flags().Lookup(name).Value.Set(strings.Join(v.GetStringSlice(name), ","))
+				values := v.GetStringSlice(name)
+				if s, ok := v.Get(name).(string); ok {
+					// from environment, "const,var" or "const var"
+					values = splitList(s)
+				}
+				cmd.Flags().Lookup(name).Value.Set(strings.Join(values, ","))
+			case "priority":
+				if s, ok := v.Get(name).(string); ok {
+					// from environment, "type=Config,Options;func=New"
+					for _, value := range strings.Split(s, ";") {
+						cmd.Flags().Lookup(name).Value.Set(value)
+					}
+					break
+				}
+				for kind, names := range v.GetStringMapStringSlice(name) {
+					cmd.Flags().Lookup(name).Value.Set(kind + "=" + strings.Join(names, ","))
+				}
 			default:
 				val := v.GetString(name)
 				cmd.Flags().Set(name, fmt.Sprintf("%v", val))
 			}
+			cmd.Flags().SetAnnotation(name, sourceAnnotation, []string{configSource(v, name, profile)})
 		}
 	})
 }
 
-func printConfigFile(config *ReorderConfig) error {
-	// for all flags, get the current value and set it to conf
-	enc := yaml.NewEncoder(defaultOutpout)
+// configSource returns where the value of the key, that is not given by a flag, comes from.
+func configSource(v *viper.Viper, name, profile string) string {
+	env := envName(name)
+	if val, ok := os.LookupEnv(env); ok && val != "" {
+		return "env " + env
+	}
+	if v.InConfig(name) {
+		return "file " + v.ConfigFileUsed()
+	}
+	if _, ok := profiles[profile][name]; ok {
+		return "profile " + profile
+	}
+	return "default"
+}
+
+// currentProfile returns the profile to use, from the flag or from viper (file or environment).
+func currentProfile(cmd *cobra.Command, v *viper.Viper) string {
+	if f := cmd.Flags().Lookup("profile"); f != nil && f.Changed {
+		return f.Value.String()
+	}
+	return v.GetString("profile")
+}
+
+// envName returns the environment variable name for a configuration key.
+func envName(name string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// explainConfig writes the configuration with, for each key, where its value comes from.
+func explainConfig(w io.Writer, config *ReorderConfig, flags *pflag.FlagSet) error {
+	var doc yaml.Node
+	if err := doc.Encode(config); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key := doc.Content[i]
+		source := "default"
+		if f := flags.Lookup(key.Value); f != nil {
+			if s, ok := f.Annotations[sourceAnnotation]; ok {
+				source = s[0]
+			} else if f.Changed {
+				source = "flag --" + f.Name
+			}
+		}
+		// an empty list or map is written on the line of the key, where yaml.v3 drops the
+		// comment of the key
+		if value := doc.Content[i+1]; len(value.Content) == 0 && (value.Kind == yaml.SequenceNode || value.Kind == yaml.MappingNode) {
+			value.LineComment = "from " + source
+		} else {
+			key.LineComment = "from " + source
+		}
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	return enc.Encode(&doc)
+}
+
+func encodeConfig(w io.Writer, config *ReorderConfig) error {
+	enc := yaml.NewEncoder(w)
 	enc.SetIndent(2)
 	return enc.Encode(&config)
 }
+
+func printConfigFile(config *ReorderConfig) error {
+	// for all flags, get the current value and set it to conf
+	return encodeConfig(defaultOutpout, config)
+}
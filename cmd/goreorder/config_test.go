@@ -2,7 +2,10 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"gopkg.in/yaml.v3"
@@ -108,3 +111,352 @@ order:
 	}
 
 }
+
+func TestConfigFileValidation(t *testing.T) {
+	const yamlFile = `format: gofmt
+ordr:
+- type
+order:
+- type
+- strct
+reorder-types: yes please
+`
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	filename := filepath.Join(tmpDir, ".goreorder")
+	if err := os.WriteFile(filename, []byte(yamlFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = validateConfigFile(filename, buildConfigSchema(nil))
+	if err == nil {
+		t.Fatal("an error should occur with an invalid configuration file")
+	}
+	expected := []string{
+		filename + `:2:1: unknown key "ordr", did you mean "order"?`,
+		filename + `:6:3: invalid order name "strct", did you mean "struct"?`,
+		filename + `:7:16: "reorder-types" should be a boolean`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(err.Error(), e) {
+			t.Errorf("error should contain %q, got:\n%s", e, err)
+		}
+	}
+
+	// syntax error
+	if err := os.WriteFile(filename, []byte("format: gofmt\norder: [type\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = validateConfigFile(filename, buildConfigSchema(nil))
+	if err == nil || !strings.HasPrefix(err.Error(), filename+":") {
+		t.Errorf("syntax error should be prefixed by the filename, got %v", err)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	config := &ReorderConfig{
+		FormatToolName: "gofmt",
+		DefOrder:       []string{"const", "main", "fnuc"},
+	}
+	err := validateConfig(config)
+	if err == nil {
+		t.Fatal("an error should occur with an invalid order")
+	}
+	if !strings.Contains(err.Error(), `did you mean "func"?`) {
+		t.Errorf("error should suggest func, got %v", err)
+	}
+
+	config.DefOrder = []string{"const", "main", "func"}
+	if err := validateConfig(config); err != nil {
+		t.Error(err)
+	}
+
+	// name patterns
+	config.DefOrder = []string{"var:Err*", "type:/Error$/", "func"}
+	if err := validateConfig(config); err != nil {
+		t.Error(err)
+	}
+	for _, order := range []string{"main:foo", "var:", "type:/(/", "func:[a-"} {
+		config.DefOrder = []string{order}
+		if err := validateConfig(config); err == nil {
+			t.Errorf("an error should occur with order %q", order)
+		}
+	}
+	config.DefOrder = []string{"fnuc:Must*"}
+	if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), `did you mean "func"?`) {
+		t.Errorf("error should suggest func, got %v", err)
+	}
+	config.DefOrder = []string{"func"}
+
+	config.MethodOrder = []string{"exported", "recevier"}
+	if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), `did you mean "receiver"?`) {
+		t.Errorf("error should suggest receiver, got %v", err)
+	}
+	config.MethodOrder = []string{"exported", "receiver", "wellknown", "name"}
+	if err := validateConfig(config); err != nil {
+		t.Error(err)
+	}
+
+	config.Banners, config.BannerTemplate = true, "=== {{.Title}} ==="
+	if err := validateConfig(config); err == nil {
+		t.Error("an error should occur with a banner that is not a comment")
+	}
+	config.BannerTemplate = "// {{.Title"
+	if err := validateConfig(config); err == nil {
+		t.Error("an error should occur with an invalid banner template")
+	}
+	config.BannerTemplate = ordering.DefaultBannerTemplate
+	if err := validateConfig(config); err != nil {
+		t.Error(err)
+	}
+
+	config.Spacing = "once"
+	if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), `did you mean "one"?`) {
+		t.Errorf("error should suggest one, got %v", err)
+	}
+	config.Spacing = "one"
+
+	config.FormatToolName = "gofumpt"
+	if err := validateConfig(config); err == nil {
+		t.Error("an error should occur with an invalid format tool")
+	}
+}
+
+func TestConfigSchemaCommand(t *testing.T) {
+	defaultOutpout = bytes.NewBuffer([]byte{})
+	cmd := buildMainCommand()
+	cmd.SetArgs([]string{"config", "schema"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	schema := struct {
+		Properties map[string]struct {
+			Type        string
+			Description string
+		}
+	}{}
+	if err := json.Unmarshal(defaultOutpout.(*bytes.Buffer).Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	for key, typ := range map[string]string{"format": "string", "order": "array", "write": "boolean"} {
+		prop, ok := schema.Properties[key]
+		if !ok {
+			t.Errorf("%s should be in the schema", key)
+			continue
+		}
+		if prop.Type != typ {
+			t.Errorf("%s should be a %s, got %s", key, typ, prop.Type)
+		}
+		if prop.Description == "" {
+			t.Errorf("%s should have a description", key)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	const yamlFile = `
+profile: godoc
+reorder-types: false
+`
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(currentDir)
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	os.Chdir(tmpDir)
+	if err := os.WriteFile(".goreorder", []byte(yamlFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ReorderConfig{FormatToolName: "gofmt"}
+	reorderCommand := buildReorderCommand(config)
+	if err := initializeViper(reorderCommand); err != nil {
+		t.Fatal(err)
+	}
+	// order comes from the profile, reorder-types is overridden by the file
+	if order := reorderCommand.Flag("order").Value.String(); order != "[const,var,func,interface,type]" {
+		t.Errorf("order should come from the godoc profile, got %s", order)
+	}
+	if config.ReorderTypes {
+		t.Error("reorder-types should be overridden by the configuration file")
+	}
+
+	// flags override the profile too
+	config = &ReorderConfig{FormatToolName: "gofmt"}
+	reorderCommand = buildReorderCommand(config)
+	reorderCommand.ParseFlags([]string{"--profile", "stepdown", "--order", "func"})
+	if err := initializeViper(reorderCommand); err != nil {
+		t.Fatal(err)
+	}
+	if config.Profile != "stepdown" {
+		t.Errorf("profile should be stepdown, got %s", config.Profile)
+	}
+	if order := reorderCommand.Flag("order").Value.String(); order != "[func]" {
+		t.Errorf("order should come from the flag, got %s", order)
+	}
+
+	// unknown profile
+	config = &ReorderConfig{FormatToolName: "gofmt"}
+	reorderCommand = buildReorderCommand(config)
+	reorderCommand.ParseFlags([]string{"--profile", "uberr"})
+	err = initializeViper(reorderCommand)
+	if err == nil || !strings.Contains(err.Error(), `did you mean "uber"?`) {
+		t.Errorf("an error should suggest the uber profile, got %v", err)
+	}
+}
+
+func TestProfileBehaviour(t *testing.T) {
+	const source = `package p
+
+func run() { apply() }
+
+func apply() {}
+
+type T struct{}
+
+func (t T) unexported() {}
+
+func (t T) Exported() {}
+`
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(currentDir)
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	os.Chdir(tmpDir)
+	if err := os.WriteFile("p.go", []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// each check is the list of declarations in the expected order
+	tests := map[string][][]string{
+		"default":  {{"type T", "func apply", "func run"}, {"func (t T) Exported", "func (t T) unexported"}},
+		"uber":     {{"type T", "func apply"}, {"func (t T) Exported", "func (t T) unexported"}},
+		"stepdown": {{"type T", "func run", "func apply"}},
+		"godoc":    {{"func apply", "func run", "type T"}},
+	}
+	for profile, checks := range tests {
+		output := bytes.NewBuffer(nil)
+		defaultOutpout = output
+		cmd := buildMainCommand()
+		cmd.SetArgs([]string{"reorder", "--profile", profile, "p.go"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		for _, check := range checks {
+			previous := -1
+			for _, decl := range check {
+				index := strings.Index(output.String(), decl)
+				if index < 0 || index < previous {
+					t.Errorf("profile %s: expected the order %v, got:\n%s", profile, check, output)
+					break
+				}
+				previous = index
+			}
+		}
+	}
+	defaultOutpout = bytes.NewBuffer(nil)
+}
+
+func TestPrintConfigExplain(t *testing.T) {
+	const yamlFile = `
+profile: godoc
+write: true
+`
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(currentDir)
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	os.Chdir(tmpDir)
+	if err := os.WriteFile(".goreorder", []byte(yamlFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOREORDER_ORDER", "var, const")
+	t.Setenv("GOREORDER_REORDER_TYPES", "false")
+
+	defaultOutpout = bytes.NewBuffer([]byte{})
+	cmd := buildMainCommand()
+	cmd.SetArgs([]string{"print-config", "--explain"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	output := defaultOutpout.(*bytes.Buffer).String()
+	for _, expected := range []string{
+		"profile: godoc # from file ",
+		"write: true # from file ",
+		"order: # from env GOREORDER_ORDER\n  - var\n  - const\n",
+		"reorder-types: false # from env GOREORDER_REORDER_TYPES",
+		"format: gofmt # from default",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("output should contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	// the output is still a valid configuration
+	conf := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(output), &conf); err != nil {
+		t.Error(err)
+	}
+
+	// an empty list is written on the line of the key
+	if err := os.WriteFile(".goreorder", []byte("order: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv("GOREORDER_ORDER")
+	defaultOutpout = bytes.NewBuffer([]byte{})
+	cmd = buildMainCommand()
+	cmd.SetArgs([]string{"print-config", "--explain"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	output = defaultOutpout.(*bytes.Buffer).String()
+	if !strings.Contains(output, "order: [] # from file ") {
+		t.Errorf("the source of an empty list should be given, got:\n%s", output)
+	}
+}
+
+func TestPriorityFlag(t *testing.T) {
+	config := &ReorderConfig{FormatToolName: "gofmt"}
+	reorderCommand := buildReorderCommand(config)
+	err := reorderCommand.ParseFlags([]string{"--priority", "type=Config,Options", "--priority", "func=New"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Priority["type"]) != 2 || config.Priority["type"][1] != "Options" {
+		t.Errorf("type priority should be Config,Options, got %v", config.Priority["type"])
+	}
+	if value := reorderCommand.Flag("priority").Value.String(); value != "func=New;type=Config,Options" {
+		t.Errorf("unexpected flag value %q", value)
+	}
+	if err := validateConfig(config); err != nil {
+		t.Error(err)
+	}
+
+	if err := reorderCommand.ParseFlags([]string{"--priority", "Config"}); err == nil {
+		t.Error("an error should occur without a kind")
+	}
+	config.Priority["tpye"] = []string{"Foo"}
+	if err := validateConfig(config); err == nil || !strings.Contains(err.Error(), `did you mean "type"?`) {
+		t.Errorf("error should suggest type, got %v", err)
+	}
+}
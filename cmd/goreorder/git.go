@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runGit runs a git command in the current directory and returns its output.
+func runGit(stdin io.Reader, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// gitGoFiles returns the Go files (test files excepted) of the NUL separated output of a git
+// command giving paths relative to the root of the repository. The returned paths are relative
+// to the current directory.
+func gitGoFiles(args ...string) ([]string, error) {
+	toplevel, err := runGit(nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root := strings.TrimSpace(string(toplevel))
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	// git gives the real path of the top level directory
+	if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
+		cwd = resolved
+	}
+	out, err := runGit(nil, args...)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, path := range strings.Split(string(out), "\x00") {
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		file := filepath.Join(root, filepath.FromSlash(path))
+		if rel, err := filepath.Rel(cwd, file); err == nil {
+			file = rel
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// changedFiles returns the Go files that changed since the revision, in the working tree or
+// in the index, and the untracked ones. The deleted files are ignored.
+func changedFiles(rev string) ([]string, error) {
+	changed, err := gitGoFiles("diff", "--name-only", "-z", "--diff-filter=d", rev, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitGoFiles("ls-files", "-z", "--full-name", "--others", "--exclude-standard", "--", ":/")
+	if err != nil {
+		return nil, err
+	}
+	return append(changed, untracked...), nil
+}
+
+// stagedFiles returns the Go files that are staged for the next commit. The deleted files are
+// ignored.
+func stagedFiles() ([]string, error) {
+	return gitGoFiles("diff", "--cached", "--name-only", "-z", "--diff-filter=d", "--")
+}
+
+// processStaged reorders the index version of the file, not the working tree one, so only
+// the staged changes are checked. In write mode, the result is staged, and written to the
+// working tree if the file has no unstaged changes (a partially staged file is kept as is in
+// the working tree). It returns true if the file needs to be reordered.
+func processStaged(filename string, config *ReorderConfig) (bool, error) {
+	log.Println("Processing staged file: " + filename)
+	input, err := runGit(nil, "show", ":"+gitRelative(filename))
+	if err != nil {
+		return false, err
+	}
+	output, changed, err := reorderContent(filename, input, config)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case config.Check:
+		// only report
+	case config.Write && !config.MakeDiff:
+		if changed {
+			if err := restage(filename, input, []byte(output)); err != nil {
+				return false, err
+			}
+		}
+	default:
+		io.Copy(defaultOutpout, bytes.NewBufferString(output))
+	}
+	return changed, nil
+}
+
+// restage replaces the index version of the file with the content, and the working tree file
+// if it is the same as the previous index version.
+func restage(filename string, index, content []byte) error {
+	// the path of --cacheinfo is relative to the root of the repository
+	entry, err := runGit(nil, "ls-files", "--stage", "--full-name", "-z", "--", filename)
+	if err != nil {
+		return err
+	}
+	// "<mode> <object> <stage>\t<file>"
+	info, path, _ := strings.Cut(strings.TrimSuffix(string(entry), "\x00"), "\t")
+	fields := strings.Fields(info)
+	if len(fields) < 3 || fields[2] != "0" || path == "" {
+		return errors.New("the file is not staged, or has conflicts")
+	}
+	mode := fields[0]
+
+	object, err := runGit(bytes.NewReader(content), "hash-object", "-w", "--stdin", "--path", filename)
+	if err != nil {
+		return err
+	}
+	cacheInfo := mode + "," + strings.TrimSpace(string(object)) + "," + path
+	if _, err := runGit(nil, "update-index", "--cacheinfo", cacheInfo); err != nil {
+		return err
+	}
+
+	worktree, err := os.ReadFile(filename)
+	if err != nil || !bytes.Equal(worktree, index) {
+		log.Println("Keeping the working tree version of " + filename + ", it has unstaged changes")
+		return nil
+	}
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, content, stat.Mode().Perm()); err != nil {
+		return fmt.Errorf("error while writing to file: %w", err)
+	}
+	return nil
+}
+
+// gitRelative returns the path in the form git expects for a path relative to the current
+// directory in a revision ("./file.go" rather than "file.go", which is relative to the root).
+func gitRelative(filename string) string {
+	path := filepath.ToSlash(filename)
+	if strings.HasPrefix(path, "../") {
+		return path
+	}
+	return "./" + path
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGitSelection(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(currentDir)
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	os.Chdir(tmpDir)
+
+	git := func(args ...string) string {
+		out, err := runGit(nil, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out)
+	}
+	write := func(file, content string) {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const (
+		sorted   = "package p\n\nfunc a() {}\n\nfunc c() {}\n"
+		unsorted = "package p\n\nfunc c() {}\n\nfunc a() {}\n"
+	)
+
+	git("init", "-q")
+	git("config", "user.email", "test@example.com")
+	git("config", "user.name", "test")
+	git("config", "commit.gpgsign", "false")
+	write("sub/clean.go", "package p\n")
+	write("sub/partial.go", "package p\n")
+	write("committed.go", unsorted)
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+
+	write("sub/clean.go", unsorted)
+	write("sub/partial.go", unsorted)
+	write("sub/partial_test.go", unsorted)
+	git("add", "sub")
+	write("sub/partial.go", unsorted+"\n// unstaged\n")
+	write("untracked.go", unsorted)
+	write("notes.txt", "not go")
+
+	files, err := changedFiles("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"sub/clean.go", "sub/partial.go", "untracked.go"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+	files, err = stagedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"sub/clean.go", "sub/partial.go"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+
+	// the paths are relative to the current directory
+	os.Chdir("sub")
+	files, err = changedFiles("HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"clean.go", "partial.go", "../untracked.go"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+	os.Chdir("..")
+
+	if _, err := changedFiles("unknown-revision"); err == nil {
+		t.Error("Expected an error for an unknown revision")
+	}
+
+	run := func(args ...string) error {
+		defaultOutpout, defaultErrOutpout = bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+		cmd := buildMainCommand()
+		cmd.SetArgs(append([]string{"reorder"}, args...))
+		return cmd.Execute()
+	}
+	if err := run("--staged", "--check"); err == nil {
+		t.Error("Expected the staged files to need a reordering")
+	}
+	if err := run("--staged", "--write"); err != nil {
+		t.Fatal(err)
+	}
+	if err := run("--staged", "--check"); err != nil {
+		t.Errorf("Expected the staged files to be reordered, got %v", err)
+	}
+
+	// the staged versions are reordered, the working tree only if it has no unstaged changes
+	for file, expected := range map[string]string{
+		":sub/clean.go":   sorted,
+		":sub/partial.go": sorted,
+	} {
+		if content := git("show", file); content != expected {
+			t.Errorf("Expected %s to be:\n%s\nGot:\n%s", file, expected, content)
+		}
+	}
+	for file, expected := range map[string]string{
+		"sub/clean.go":   sorted,
+		"sub/partial.go": unsorted + "\n// unstaged\n",
+		"committed.go":   unsorted,
+		"untracked.go":   unsorted,
+	} {
+		if content, _ := os.ReadFile(file); string(content) != expected {
+			t.Errorf("Expected %s to be:\n%s\nGot:\n%s", file, expected, content)
+		}
+	}
+
+	// from a sub directory, the files of the whole repository are restaged
+	write("sub/deep/more.go", unsorted)
+	write("root.go", unsorted)
+	git("add", "sub/deep/more.go", "root.go")
+	os.Chdir(filepath.Join("sub", "deep"))
+	if err := run("--staged", "--write"); err != nil {
+		t.Fatal(err)
+	}
+	os.Chdir(filepath.Join("..", ".."))
+	for _, file := range []string{"sub/deep/more.go", "root.go"} {
+		if content := git("show", ":"+file); content != sorted {
+			t.Errorf("Expected the index version of %s to be:\n%s\nGot:\n%s", file, sorted, content)
+		}
+		if content, _ := os.ReadFile(file); string(content) != sorted {
+			t.Errorf("Expected %s to be:\n%s\nGot:\n%s", file, sorted, content)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The goreorder block of the pre-commit hook is delimited by these lines, so it can be
+// updated or removed without touching the rest of an existing hook.
+const (
+	hookBegin = "# >>> goreorder >>>"
+	hookEnd   = "# <<< goreorder <<<"
+)
+
+// hookBlock checks the staged files. Git runs the hook at the root of the working tree, so the
+// .goreorder file of the repository is used.
+const hookBlock = hookBegin + `
+# installed by "goreorder hook install", remove it with "goreorder hook uninstall"
+if ! command -v goreorder >/dev/null 2>&1; then
+	echo "goreorder: command not found, install it or run \"goreorder hook uninstall\"" >&2
+	exit 1
+fi
+goreorder reorder --staged --check || {
+	echo "goreorder: fix the staged files with \"goreorder reorder --staged --write\"" >&2
+	exit 1
+}
+` + hookEnd + "\n"
+
+// preCommitHook returns the path of the pre-commit hook of the repository, core.hooksPath
+// being respected.
+func preCommitHook() (string, error) {
+	out, err := runGit(nil, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(strings.TrimSpace(string(out)), "pre-commit"), nil
+}
+
+// installHook adds the goreorder block to the pre-commit hook, or replaces it if it is
+// already installed. An existing hook is kept if it is a shell script: the block is inserted
+// right after the shebang, so an "exit" or an "exec" of the hook cannot skip it.
+func installHook() (string, error) {
+	hook, err := preCommitHook()
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(hook)
+	switch {
+	case os.IsNotExist(err):
+		content = []byte("#!/bin/sh\n")
+	case err != nil:
+		return "", err
+	case !isShellScript(content):
+		return "", fmt.Errorf("%s is not a shell script, add \"goreorder reorder --staged --check\" to it manually", hook)
+	}
+
+	script, _ := removeHookBlock(string(content))
+	shebang, body := "", script
+	if strings.HasPrefix(script, "#!") {
+		line, rest, _ := strings.Cut(script, "\n")
+		shebang, body = line+"\n\n", rest
+	}
+	script = shebang + hookBlock
+	if strings.TrimSpace(body) != "" {
+		script += "\n" + body
+	}
+	if err := os.MkdirAll(filepath.Dir(hook), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(hook, []byte(script), 0755); err != nil {
+		return "", err
+	}
+	// WriteFile does not change the mode of an existing file
+	return hook, os.Chmod(hook, 0755)
+}
+
+// uninstallHook removes the goreorder block from the pre-commit hook, and the hook itself if
+// nothing else remains.
+func uninstallHook() (string, error) {
+	hook, err := preCommitHook()
+	if err != nil {
+		return "", err
+	}
+	content, err := os.ReadFile(hook)
+	if os.IsNotExist(err) {
+		return "", errors.New("the goreorder hook is not installed")
+	}
+	if err != nil {
+		return "", err
+	}
+	script, found := removeHookBlock(string(content))
+	if !found {
+		return "", errors.New("the goreorder hook is not installed")
+	}
+	if strings.TrimSpace(script) == "#!/bin/sh" {
+		return hook, os.Remove(hook)
+	}
+	return hook, os.WriteFile(hook, []byte(script), 0755)
+}
+
+// removeHookBlock returns the script without the goreorder block and the blank lines added
+// around it, and true if the block was found.
+func removeHookBlock(script string) (string, bool) {
+	start := strings.Index(script, hookBegin+"\n")
+	if start < 0 {
+		return script, false
+	}
+	end := strings.Index(script[start:], hookEnd)
+	if end < 0 {
+		return script, false
+	}
+	end += start + len(hookEnd)
+	if strings.HasPrefix(script[end:], "\n") {
+		end++
+	}
+	if strings.HasSuffix(script[:start], "\n\n") {
+		start--
+	}
+	if strings.HasPrefix(script[end:], "\n") {
+		end++
+	}
+	return script[:start] + script[end:], true
+}
+
+// isShellScript returns true if the script has no shebang, or a shell one.
+func isShellScript(content []byte) bool {
+	line, _, _ := strings.Cut(string(content), "\n")
+	if !strings.HasPrefix(line, "#!") {
+		return true
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return false
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = fields[1]
+	}
+	switch interpreter {
+	case "sh", "bash", "dash", "ksh", "zsh":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHook(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(currentDir)
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	os.Chdir(tmpDir)
+	if _, err := runGit(nil, "init", "-q"); err != nil {
+		t.Fatal(err)
+	}
+	hook := filepath.Join(".git", "hooks", "pre-commit")
+	os.Remove(hook)
+
+	// new hook, installing twice does not duplicate the block
+	for i := 0; i < 2; i++ {
+		if _, err := installHook(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content, _ := os.ReadFile(hook)
+	if expected := "#!/bin/sh\n\n" + hookBlock; string(content) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, content)
+	}
+	if stat, _ := os.Stat(hook); stat.Mode().Perm()&0100 == 0 {
+		t.Error("The hook should be executable")
+	}
+	if _, err := uninstallHook(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(hook); !os.IsNotExist(err) {
+		t.Error("The hook should be removed when nothing else remains")
+	}
+	if _, err := uninstallHook(); err == nil {
+		t.Error("Expected an error when the hook is not installed")
+	}
+
+	// existing hooks, the block is inserted after the shebang so an exit or an exec cannot
+	// skip it, and the hook is restored on uninstall
+	for _, existing := range []string{
+		"#!/usr/bin/env bash\necho checking\nexit 0\n",
+		"#!/bin/sh\nexec golangci-lint run\n",
+		"#!/usr/bin/env bash\nif command -v pre-commit > /dev/null; then\n    exec pre-commit \"$@\"\nelse\n    exit 1\nfi\n",
+	} {
+		os.WriteFile(hook, []byte(existing), 0644)
+		if _, err := installHook(); err != nil {
+			t.Fatal(err)
+		}
+		content, _ = os.ReadFile(hook)
+		shebang, body, _ := strings.Cut(existing, "\n")
+		if expected := shebang + "\n\n" + hookBlock + "\n" + body; string(content) != expected {
+			t.Errorf("Expected:\n%s\nGot:\n%s", expected, content)
+		}
+		if _, err := uninstallHook(); err != nil {
+			t.Fatal(err)
+		}
+		if content, _ = os.ReadFile(hook); string(content) != existing {
+			t.Errorf("Expected the existing hook to be restored:\n%s\nGot:\n%s", existing, content)
+		}
+	}
+
+	// the block is run before the rest of the hook
+	sh, shErr := exec.LookPath("sh")
+	truePath, trueErr := exec.LookPath("true")
+	if shErr == nil && trueErr == nil {
+		os.WriteFile(hook, []byte("#!/bin/sh\nexec "+truePath+"\n"), 0755)
+		if _, err := installHook(); err != nil {
+			t.Fatal(err)
+		}
+		// goreorder is not found with an empty path, the block fails
+		cmd := exec.Command(sh, hook)
+		cmd.Env = []string{"PATH=" + tmpDir}
+		if err := cmd.Run(); err == nil {
+			t.Error("The goreorder block should run before the exec of the hook")
+		}
+		os.Remove(hook)
+	}
+
+	// other languages are not changed
+	const python = "#!/usr/bin/env python3\nprint('checking')\n"
+	os.WriteFile(hook, []byte(python), 0755)
+	if _, err := installHook(); err == nil {
+		t.Error("Expected an error for a hook that is not a shell script")
+	}
+	if content, _ := os.ReadFile(hook); string(content) != python {
+		t.Errorf("The hook should not be changed, got:\n%s", content)
+	}
+}
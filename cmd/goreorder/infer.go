@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/metal3d/goreorder/ordering"
+)
+
+// styleDecl is a top-level declaration found while inferring the style.
+type styleDecl struct {
+	kind  string
+	line  int
+	lines int
+}
+
+// styleStats contains the measures made on the existing sources.
+type styleStats struct {
+	Files        int
+	Precedes     map[string]map[string]int // Precedes[a][b] is the number of times a kind a is found before a kind b
+	TypeFiles    int                       // files having at least two types
+	SortedTypes  int                       // files where types are already alphabetized
+	declsPerFile [][]styleDecl
+}
+
+// newStyleStats returns an empty styleStats.
+func newStyleStats() *styleStats {
+	return &styleStats{
+		Precedes: make(map[string]map[string]int),
+	}
+}
+
+// add collects the measures of a parsed file.
+func (s *styleStats) add(info *ordering.ParsedInfo) {
+	s.Files++
+
+	seen := map[int]bool{}
+	decls := []styleDecl{}
+	appendDecl := func(kind string, t *ordering.GoType) {
+		// grouped declarations share the same lines
+		if seen[t.OpeningLine] {
+			return
+		}
+		seen[t.OpeningLine] = true
+		decls = append(decls, styleDecl{kind: kind, line: t.OpeningLine, lines: t.ClosingLine - t.OpeningLine + 1})
+	}
+	for _, t := range info.Constants {
+		appendDecl(ordering.Const, t)
+	}
+	for _, t := range info.Variables {
+		appendDecl(ordering.Var, t)
+	}
+	for _, t := range info.Interfaces {
+		appendDecl(ordering.Interface, t)
+	}
+	for name, t := range info.Types {
+		appendDecl(ordering.Type, t)
+		// constructors and methods are placed with their type
+		for _, c := range info.Constructors[name] {
+			appendDecl(ordering.Type, c)
+		}
+		for _, m := range info.Methods[name] {
+			appendDecl(ordering.Type, m)
+		}
+	}
+	for name, t := range info.Functions {
+		switch name {
+		case "init":
+			appendDecl(ordering.Init, t)
+		case "main":
+			appendDecl(ordering.Main, t)
+		default:
+			appendDecl(ordering.Func, t)
+		}
+	}
+	sort.Slice(decls, func(i, j int) bool {
+		return decls[i].line < decls[j].line
+	})
+	s.declsPerFile = append(s.declsPerFile, decls)
+
+	for i, a := range decls {
+		for _, b := range decls[i+1:] {
+			if a.kind == b.kind {
+				continue
+			}
+			if s.Precedes[a.kind] == nil {
+				s.Precedes[a.kind] = map[string]int{}
+			}
+			s.Precedes[a.kind][b.kind]++
+		}
+	}
+
+	if info.TypeNames.Len() > 1 {
+		s.TypeFiles++
+		if sort.StringsAreSorted(*info.TypeNames) {
+			s.SortedTypes++
+		}
+	}
+}
+
+// churn returns the number of lines that would be moved with the given order.
+// The lines that are kept in place are the heaviest non decreasing subsequence of ranks.
+func (s *styleStats) churn(order []string) int {
+	rank := map[string]int{}
+	for i, kind := range order {
+		rank[kind] = i
+	}
+	// init and main are functions if they are not in the list
+	for _, kind := range []string{ordering.Init, ordering.Main} {
+		if _, ok := rank[kind]; !ok {
+			rank[kind] = rank[ordering.Func]
+		}
+	}
+
+	moved := 0
+	for _, decls := range s.declsPerFile {
+		best := make([]int, len(order))
+		total := 0
+		for _, d := range decls {
+			r := rank[d.kind]
+			kept := 0
+			for _, b := range best[:r+1] {
+				kept = max(kept, b)
+			}
+			best[r] = max(best[r], kept+d.lines)
+			total += d.lines
+		}
+		kept := 0
+		for _, b := range best {
+			kept = max(kept, b)
+		}
+		moved += total - kept
+	}
+	return moved
+}
+
+// inferOrder returns the order that moves the fewest lines, and the number of moved lines.
+// The default order is preferred when several orders are equivalent.
+func (s *styleStats) inferOrder() ([]string, int) {
+	bestOrder := append([]string{}, ordering.DefaultOrder...)
+	bestChurn := s.churn(bestOrder)
+
+	permute(append([]string{}, ordering.DefaultOrder...), func(kinds []string) {
+		// init and main can be considered as functions, or be placed anywhere
+		for initPos := -1; initPos <= len(kinds); initPos++ {
+			withInit := insertAt(append([]string{}, kinds...), initPos, ordering.Init)
+			for mainPos := -1; mainPos <= len(withInit); mainPos++ {
+				candidate := insertAt(append([]string{}, withInit...), mainPos, ordering.Main)
+				if c := s.churn(candidate); c < bestChurn {
+					bestOrder, bestChurn = candidate, c
+				}
+			}
+		}
+	})
+	return bestOrder, bestChurn
+}
+
+// report writes a human readable summary of the measures.
+func (s *styleStats) report(w io.Writer, order []string, churn int) {
+	kinds := append(append([]string{}, ordering.DefaultOrder...), ordering.Init, ordering.Main)
+	fmt.Fprintf(w, "Analyzed %d file(s)\n", s.Files)
+	fmt.Fprintf(w, "\nHow often a kind (row) is found before another (column):\n%-10s", "")
+	for _, k := range kinds {
+		fmt.Fprintf(w, "%10s", k)
+	}
+	fmt.Fprintln(w)
+	for _, a := range kinds {
+		fmt.Fprintf(w, "%-10s", a)
+		for _, b := range kinds {
+			fmt.Fprintf(w, "%10d", s.Precedes[a][b])
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "\nTypes already sorted in %d/%d file(s)\n", s.SortedTypes, s.TypeFiles)
+	fmt.Fprintf(w, "Proposed order: %s (%d line(s) to move)\n", strings.Join(order, ","), churn)
+}
+
+// inferConfig parses the Go files in the directory (recursively) and returns the configuration
+// that would change the fewest lines.
+func inferConfig(directory string, w io.Writer) (*ReorderConfig, error) {
+	stats := newStyleStats()
+	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != directory && skipDirectory(info.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		parsed, err := ordering.Parse(path, nil)
+		if err != nil {
+			log.Println("Skipping file", path, err)
+			return nil
+		}
+		stats.add(parsed)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if stats.Files == 0 {
+		return nil, fmt.Errorf("no Go file found in %s", directory)
+	}
+
+	order, churn := stats.inferOrder()
+	stats.report(w, order, churn)
+
+	// the other keys keep their default value, so the file is valid
+	config := defaultConfig()
+	config.DefOrder = order
+	// types are only sorted if it's already the case everywhere
+	config.ReorderTypes = stats.TypeFiles > 0 && stats.SortedTypes == stats.TypeFiles
+	return config, nil
+}
+
+// insertAt inserts value at the given position, a negative position means "do not insert".
+func insertAt(list []string, pos int, value string) []string {
+	if pos < 0 {
+		return list
+	}
+	return append(list[:pos], append([]string{value}, list[pos:]...)...)
+}
+
+// permute calls fn with every permutation of list (Heap's algorithm), the first one
+// being the list itself.
+func permute(list []string, fn func([]string)) {
+	var generate func(int)
+	generate = func(k int) {
+		if k <= 1 {
+			fn(list)
+			return
+		}
+		for i := 0; i < k; i++ {
+			generate(k - 1)
+			if k%2 == 0 {
+				list[i], list[k-1] = list[k-1], list[i]
+			} else {
+				list[0], list[k-1] = list[k-1], list[0]
+			}
+		}
+	}
+	generate(len(list))
+}
+
+// skipDirectory returns true for the directories that the go tool ignores.
+func skipDirectory(name string) bool {
+	return name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// runKeys are the keys of the options that change what a run does, not the ordering style.
+// They are left out of the file written by init, so "goreorder reorder" keeps its defaults.
+var runKeys = map[string]bool{"write": true, "verbose": true, "diff": true, "check": true}
+
+// writeConfigFile writes the configuration to the file, without the runKeys.
+func writeConfigFile(filename string, config *ReorderConfig, force bool) error {
+	if _, err := os.Stat(filename); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", filename)
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(config); err != nil {
+		return err
+	}
+	content := []*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !runKeys[node.Content[i].Value] {
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+	}
+	node.Content = content
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	enc := yaml.NewEncoder(file)
+	enc.SetIndent(2)
+	return enc.Encode(node)
+}
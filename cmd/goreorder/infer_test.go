@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/metal3d/goreorder/ordering"
+)
+
+func TestInferOrder(t *testing.T) {
+	files := map[string]string{
+		"a.go": `package foo
+
+var a = 1
+
+const A = 1
+
+type Foo struct{}
+
+func (f Foo) A() {}
+func (f Foo) B() {}
+
+func init() {}
+
+func bar() {}
+`,
+		"b.go": `package foo
+
+var b = 1
+
+const B = 1
+
+type Bar struct{}
+type Baz struct{}
+
+func init() {}
+
+func baz() {}
+`,
+	}
+	stats := newStyleStats()
+	for name, content := range files {
+		info, err := ordering.Parse(name, []byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats.add(info)
+	}
+
+	if stats.Precedes[ordering.Var][ordering.Const] != 2 {
+		t.Errorf("var should be found 2 times before const, got %d", stats.Precedes[ordering.Var][ordering.Const])
+	}
+	if stats.SortedTypes != 1 {
+		t.Errorf("types should be sorted in 1 file, got %d", stats.SortedTypes)
+	}
+
+	order, churn := stats.inferOrder()
+	if churn != 0 {
+		t.Errorf("no line should move, got %d", churn)
+	}
+	if strings.Join(order, ",") != "var,const,interface,type,func" {
+		t.Errorf("unexpected order %v", order)
+	}
+
+	// with the default order, the const declarations are moved
+	if c := stats.churn(ordering.DefaultOrder); c != 2 {
+		t.Errorf("default order should move 2 lines, got %d", c)
+	}
+}
+
+func TestInitCommand(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	source := "package foo\n\nvar a = 1\n\nconst A = 1\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "foo.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := buildMainCommand()
+	cmd.SetArgs([]string{"init", tmpDir})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDir, ".goreorder"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(content, []byte("order:\n  - var\n  - const\n")) {
+		t.Errorf("var should be placed before const, got:\n%s", content)
+	}
+	for key := range runKeys {
+		if bytes.Contains(content, []byte("\n"+key+":")) || bytes.HasPrefix(content, []byte(key+":")) {
+			t.Errorf("%s should not be written, got:\n%s", key, content)
+		}
+	}
+
+	// the written configuration is valid, reorder uses it
+	output := bytes.NewBuffer(nil)
+	defaultOutpout = output
+	defer func() { defaultOutpout = bytes.NewBuffer(nil) }()
+	cmd = buildMainCommand()
+	cmd.SetArgs([]string{"reorder", filepath.Join(tmpDir, "foo.go")})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("reorder failed with the configuration written by init: %v\n%s", err, content)
+	}
+	if output.String() != source {
+		t.Errorf("Expected the source unchanged with the inferred order, got:\n%s", output)
+	}
+
+	// the file exists now
+	cmd = buildMainCommand()
+	cmd.SetArgs([]string{"init", tmpDir})
+	if err := cmd.Execute(); err == nil {
+		t.Error("an error should occur if the file already exists")
+	}
+	cmd = buildMainCommand()
+	cmd.SetArgs([]string{"init", "--force", tmpDir})
+	if err := cmd.Execute(); err != nil {
+		t.Error(err)
+	}
+}
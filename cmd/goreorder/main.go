@@ -2,10 +2,13 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"go/scanner"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	logger "github.com/metal3d/goreorder/log"
@@ -18,114 +21,361 @@ var (
 
 func main() {
 	if err := buildMainCommand().Execute(); err != nil {
+		var exit *exitError
+		if errors.As(err, &exit) {
+			// the errors are already printed
+			os.Exit(exit.code)
+		}
 		io.WriteString(defaultErrOutpout, fmt.Sprintf("%s\n", err))
-		os.Exit(1)
+		os.Exit(exitErrors)
 	}
 }
 
 // ReorderConfig is the configuration for the reorder command
 type ReorderConfig struct {
-	FormatToolName string   `yaml:"format"`
-	DefOrder       []string `yaml:"order"`
-	Write          bool     `yaml:"write"`
-	Verbose        bool     `yaml:"verbose"`
-	ReorderTypes   bool     `yaml:"reorder-types"`
-	MakeDiff       bool     `yaml:"diff"`
+	Profile              string              `yaml:"profile,omitempty"`
+	FormatToolName       string              `yaml:"format"`
+	DefOrder             []string            `yaml:"order"`
+	Write                bool                `yaml:"write"`
+	Verbose              bool                `yaml:"verbose"`
+	ReorderTypes         bool                `yaml:"reorder-types"`
+	MakeDiff             bool                `yaml:"diff"`
+	Priority             map[string][]string `yaml:"priority,omitempty"`
+	Associate            bool                `yaml:"associate"`
+	Assertions           string              `yaml:"assertions"`
+	Stepdown             bool                `yaml:"stepdown"`
+	ColocateHelpers      bool                `yaml:"colocate-helpers"`
+	GroupByInterface     bool                `yaml:"group-by-interface"`
+	MethodOrder          []string            `yaml:"method-order"`
+	PairAccessors        bool                `yaml:"pair-accessors"`
+	SortStructFields     bool                `yaml:"sort-struct-fields"`
+	SortInterfaceMethods bool                `yaml:"sort-interface-methods"`
+	AlignStructs         bool                `yaml:"align-structs"`
+	AlignArch            string              `yaml:"align-arch"`
+	Banners              bool                `yaml:"banners"`
+	BannerTemplate       string              `yaml:"banner-template"`
+	Spacing              string              `yaml:"spacing"`
+	Check                bool                `yaml:"check"`
+	StdinFilename        string              `yaml:"-"`
+	FilesFrom            string              `yaml:"-"`
+	NullSeparated        bool                `yaml:"-"`
+	ChangedSince         string              `yaml:"-"`
+	Staged               bool                `yaml:"-"`
+}
+
+// orderingConfig returns the configuration for the ordering package.
+func orderingConfig(config *ReorderConfig, filename string, input []byte) ordering.ReorderConfig {
+	var interfaces []ordering.MethodSet
+	if config.GroupByInterface {
+		interfaces = packageInterfaces(filename)
+	}
+	return ordering.ReorderConfig{
+		Filename:             filename,
+		FormatCommand:        config.FormatToolName,
+		ReorderStructs:       config.ReorderTypes,
+		Diff:                 config.MakeDiff,
+		DefOrder:             config.DefOrder,
+		Priority:             config.Priority,
+		Associate:            config.Associate,
+		Assertions:           config.Assertions,
+		Stepdown:             config.Stepdown,
+		ColocateHelpers:      config.ColocateHelpers,
+		GroupByInterface:     config.GroupByInterface,
+		Interfaces:           interfaces,
+		MethodOrder:          config.MethodOrder,
+		PairAccessors:        config.PairAccessors,
+		SortStructFields:     config.SortStructFields,
+		SortInterfaceMethods: config.SortInterfaceMethods,
+		AlignStructs:         config.AlignStructs,
+		AlignArch:            config.AlignArch,
+		Banners:              config.Banners,
+		BannerTemplate:       config.BannerTemplate,
+		Spacing:              config.Spacing,
+		Src:                  input,
+	}
+}
+
+// interfacesByDirectory caches the interfaces declared in each file of a directory.
+var interfacesByDirectory = map[string]map[string][]ordering.MethodSet{}
+
+// packageInterfaces returns the interfaces declared in the other Go files of the directory of
+// filename (test files excepted). Nothing is returned if filename does not exist, e.g. stdin.
+func packageInterfaces(filename string) []ordering.MethodSet {
+	if _, err := os.Stat(filename); err != nil {
+		return nil
+	}
+	directory := filepath.Dir(filename)
+	files, ok := interfacesByDirectory[directory]
+	if !ok {
+		files = map[string][]ordering.MethodSet{}
+		paths, _ := filepath.Glob(filepath.Join(directory, "*.go"))
+		for _, path := range paths {
+			if strings.HasSuffix(path, "_test.go") {
+				continue
+			}
+			info, err := ordering.Parse(path, nil)
+			if err != nil {
+				log.Println("Skipping interfaces of", path, err)
+				continue
+			}
+			files[path] = info.MethodSets()
+		}
+		interfacesByDirectory[directory] = files
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		if path != filepath.Join(directory, filepath.Base(filename)) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	interfaces := []ordering.MethodSet{}
+	for _, path := range paths {
+		interfaces = append(interfaces, files[path]...)
+	}
+	return interfaces
+}
+
+const (
+	stdinArg             = "-"        // argument to read the source from stdin
+	defaultStdinFilename = "stdin.go" // name of the source read from stdin, if none is given
+)
+
+// Exit codes of the command.
+const (
+	exitOK      = 0
+	exitChanges = 1 // in check mode, some files need to be reordered
+	exitErrors  = 2 // some files could not be processed, or the command failed
+)
+
+// exitError is returned when the command must exit with a specific code, the messages being
+// already printed.
+type exitError struct {
+	code    int
+	summary string
+}
+
+func (e *exitError) Error() string {
+	return e.summary
 }
 
 func reorder(config *ReorderConfig, args ...string) error {
 
-	// is there something in stdin?
-	filename := ""
-	var input []byte
-	var err error
-	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) == 0 {
-		// read from stdin
-		input, err = io.ReadAll(os.Stdin)
+	// "-" reads the source from stdin
+	for _, arg := range args {
+		if arg == stdinArg && (len(args) > 1 || config.FilesFrom != "" || config.ChangedSince != "" || config.Staged) {
+			return fmt.Errorf("%q (stdin) cannot be used with other arguments, --files-from, --changed-since or --staged", stdinArg)
+		}
+	}
+	if len(args) == 1 && args[0] == stdinArg {
+		input, err := io.ReadAll(stdinInput)
 		if err != nil {
 			return fmt.Errorf("error while reading stdin: %w", err)
 		}
-		filename = "stdin.go"
-		config.Write = false
-		log.Println("Processing stdin, write is set to false")
-	} else {
-		// read from file or directory
-		filename = args[0]
+		if len(input) == 0 {
+			return errors.New("stdin is empty")
+		}
+		filename := config.StdinFilename
 		if filename == "" {
-			return fmt.Errorf("filename is empty")
+			filename = defaultStdinFilename
+		}
+		config.Write = false
+		log.Println("Processing stdin as " + filename + ", write is set to false")
+		changed, err := processFile(filename, input, config)
+		return exitStatus(config, 1, boolToInt(changed), errorLines(filename, err))
+	}
+
+	// the index version of the staged files
+	if config.Staged {
+		if len(args) > 0 || config.FilesFrom != "" || config.ChangedSince != "" {
+			return errors.New("--staged cannot be used with arguments, --files-from or --changed-since")
 		}
-		_, err := os.Stat(filename)
+		files, err := stagedFiles()
 		if err != nil {
-			return fmt.Errorf("error while getting file stat: %w", err)
+			return err
 		}
+		return processAll(config, files, func(file string) (bool, error) {
+			return processStaged(file, config)
+		})
 	}
 
-	return processFile(filename, input, config)
-}
-
-func processFile(fileOrDirectoryName string, input []byte, config *ReorderConfig) error {
-	if strings.HasSuffix(fileOrDirectoryName, "_test.go") {
-		return fmt.Errorf("Skipping test file: " + fileOrDirectoryName)
+	// read from files, directories and packages, and from the list of files
+	missing := []string{}
+	if config.FilesFrom != "" {
+		list, err := readFilesFrom(config.FilesFrom, config.NullSeparated)
+		if err != nil {
+			return err
+		}
+		for _, entry := range list {
+			// a missing file of the list is reported with the errors of the other files
+			if _, err := os.Stat(entry); os.IsNotExist(err) && !strings.HasSuffix(entry, "...") {
+				missing = append(missing, entry)
+				continue
+			}
+			args = append(args, entry)
+		}
 	}
-
-	if len(input) != 0 {
-		// process stdin
-		content, err := ordering.ReorderSource(ordering.ReorderConfig{
-			Filename:       fileOrDirectoryName,
-			FormatCommand:  config.FormatToolName,
-			ReorderStructs: config.ReorderTypes,
-			Diff:           config.MakeDiff,
-			Src:            input,
-		})
+	if config.ChangedSince != "" {
+		list, err := changedFiles(config.ChangedSince)
 		if err != nil {
-			return fmt.Errorf("error while reordering source: %w", err)
+			return err
 		}
-		fmt.Print(string(content))
-		return nil
+		args = append(args, list...)
 	}
-
-	stat, err := os.Stat(fileOrDirectoryName)
+	files, err := resolvePatterns(args)
 	if err != nil {
-		return fmt.Errorf("error while getting file stat: %w", err)
+		return err
 	}
-	if stat.IsDir() {
-		// skip vendor directory
-		if strings.HasSuffix(fileOrDirectoryName, "vendor") {
-			return fmt.Errorf("skipping vendor directory: " + fileOrDirectoryName)
+	files = append(files, missing...)
+	return processAll(config, files, func(file string) (bool, error) {
+		return processFile(file, nil, config)
+	})
+}
+
+// processAll processes the files, collects the errors, lists the files to reorder in check
+// mode, and returns the exit status.
+func processAll(config *ReorderConfig, files []string, process func(file string) (bool, error)) error {
+	changed := 0
+	errs := []string{}
+	for _, file := range files {
+		fileChanged, err := process(file)
+		if err != nil {
+			errs = append(errs, errorLines(file, err)...)
+			continue
 		}
-		// get all files in directory and process them
-		log.Println("Processing directory: " + fileOrDirectoryName)
-		return filepath.Walk(fileOrDirectoryName, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return fmt.Errorf("error while walking directory: %w", err)
-			}
-			if strings.HasSuffix(path, ".go") {
-				processFile(path, nil, config)
+		if fileChanged {
+			changed++
+			if config.Check {
+				fmt.Fprintln(defaultOutpout, file)
 			}
-			return nil
-		})
+		}
 	}
+	return exitStatus(config, len(files), changed, errs)
+}
 
-	log.Println("Processing file: " + fileOrDirectoryName)
-	output, err := ordering.ReorderSource(ordering.ReorderConfig{
-		Filename:       fileOrDirectoryName,
-		FormatCommand:  config.FormatToolName,
-		ReorderStructs: config.ReorderTypes,
-		Diff:           config.MakeDiff,
-		DefOrder:       config.DefOrder,
-		Src:            input,
-	})
+// readFilesFrom returns the entries of the list of files given to --files-from, a file or "-"
+// for stdin.
+func readFilesFrom(filesFrom string, nul bool) ([]string, error) {
+	if filesFrom == stdinArg {
+		return readFileList(stdinInput, nul)
+	}
+	file, err := os.Open(filesFrom)
 	if err != nil {
-		return fmt.Errorf("error while reordering file: %w", err)
+		return nil, fmt.Errorf("error while reading the file list: %w", err)
+	}
+	defer file.Close()
+	return readFileList(file, nul)
+}
+
+// exitStatus prints the errors and the summary, and returns the error that gives the exit
+// code, or nil if everything is fine. The summary is only printed for several files, in
+// check mode, or if there are errors.
+func exitStatus(config *ReorderConfig, files, changed int, errs []string) error {
+	for _, line := range errs {
+		fmt.Fprintln(defaultErrOutpout, line)
+	}
+	action := "reordered"
+	if config.Check || (!config.Write && !config.MakeDiff) {
+		action = "to reorder"
+	}
+	summary := fmt.Sprintf("%d file(s) processed, %d %s, %d error(s)", files, changed, action, len(errs))
+	if files > 1 || config.Check || len(errs) > 0 {
+		fmt.Fprintln(defaultErrOutpout, summary)
+	}
+
+	switch {
+	case len(errs) > 0:
+		return &exitError{code: exitErrors, summary: summary}
+	case config.Check && changed > 0:
+		return &exitError{code: exitChanges, summary: summary}
+	}
+	return nil
+}
+
+// errorLines returns the error messages in the gofmt style, "file:line:col: message" for the
+// parse errors, or "file: message".
+func errorLines(filename string, err error) []string {
+	if err == nil {
+		return nil
+	}
+	var parseErr *ordering.ParseError
+	if !errors.As(err, &parseErr) {
+		return []string{filename + ": " + err.Error()}
 	}
-	if config.Write {
-		err = os.WriteFile(fileOrDirectoryName, []byte(output), 0644)
+	var list scanner.ErrorList
+	if errors.As(parseErr, &list) {
+		lines := make([]string, len(list))
+		for i, e := range list {
+			lines[i] = e.Error()
+		}
+		return lines
+	}
+	return []string{parseErr.Error()}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// processFile reorders the file, or the given input (read from stdin) if it is not nil. The
+// result is written to the file, printed, or only compared in check mode. It returns true if
+// the file needs to be reordered.
+func processFile(filename string, input []byte, config *ReorderConfig) (bool, error) {
+	fromStdin := input != nil
+	if strings.HasSuffix(filename, "_test.go") {
+		if !fromStdin {
+			return false, fmt.Errorf("Skipping test file: " + filename)
+		}
+		// an editor pipes the test files too, they are given back unchanged
+		if !config.Check && !config.MakeDiff {
+			defaultOutpout.Write(input)
+		}
+		return false, nil
+	}
+
+	if !fromStdin {
+		log.Println("Processing file: " + filename)
+		content, err := os.ReadFile(filename)
 		if err != nil {
-			return fmt.Errorf("error while writing to file: %w", err)
+			return false, fmt.Errorf("error while reading file: %w", err)
 		}
-	} else {
+		input = content
+	}
+
+	output, changed, err := reorderContent(filename, input, config)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case config.Check:
+		// only report
+	case config.Write && !fromStdin:
+		if changed {
+			if err := os.WriteFile(filename, []byte(output), 0644); err != nil {
+				return false, fmt.Errorf("error while writing to file: %w", err)
+			}
+		}
+	default:
 		io.Copy(defaultOutpout, bytes.NewBufferString(output))
 	}
-	return nil
+	return changed, nil
+}
+
+// reorderContent returns the reordered source, or the diff in diff mode, and true if the source
+// needs to be reordered.
+func reorderContent(filename string, input []byte, config *ReorderConfig) (string, bool, error) {
+	output, err := ordering.ReorderSource(orderingConfig(config, filename, input))
+	if err != nil {
+		return "", false, err
+	}
+	changed := output != string(input)
+	if config.MakeDiff {
+		changed = strings.TrimSpace(output) != ""
+	}
+	return output, changed, nil
 }
@@ -2,8 +2,10 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -98,7 +100,7 @@ func (c C) C() {}`),
 
 	// launch command
 	cmd := buildMainCommand()
-	cmd.SetArgs([]string{"reorder", "--write", "./"})
+	cmd.SetArgs([]string{"reorder", "--write", "./..."})
 	cmd.Execute()
 
 	// check files
@@ -171,3 +173,197 @@ func TestCompletionCommands(t *testing.T) {
 		t.Error("an error should occur with a bad shell argument", err)
 	}
 }
+
+func TestPackageInterfaces(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	files := map[string]string{
+		"shape.go": `package shapes
+type Shape interface {
+	Perimeter() float64
+	Area() float64
+}`,
+		"square.go": `package shapes
+type Square struct{}
+func (s Square) Area() float64 { return 0 }
+func (s Square) Perimeter() float64 { return 0 }
+`,
+		"shape_test.go": `package shapes
+type Tested interface { Area() float64 }`,
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	square := filepath.Join(tmpDir, "square.go")
+	interfaces := packageInterfaces(square)
+	if len(interfaces) != 1 || interfaces[0].Name != "Shape" {
+		t.Fatalf("expected the Shape interface only, got %v", interfaces)
+	}
+
+	cmd := buildMainCommand()
+	cmd.SetArgs([]string{"reorder", "--write", "--group-by-interface", square})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(square)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Index(string(content), "Perimeter") > strings.Index(string(content), "Area") {
+		t.Errorf("Perimeter should be placed before Area, as in Shape:\n%s", content)
+	}
+}
+
+func TestCheckAndErrors(t *testing.T) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(currentDir)
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	os.Chdir(tmpDir)
+
+	files := map[string]string{
+		"unsorted.go": "package p\n\nfunc b() {}\n\nfunc a() {}\n",
+		"sorted.go":   "package p\n\nfunc a() {}\n",
+	}
+	for file, content := range files {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(args ...string) (string, string, int) {
+		out, errOut := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+		defaultOutpout, defaultErrOutpout = out, errOut
+		defer func() {
+			defaultOutpout, defaultErrOutpout = bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+		}()
+		cmd := buildMainCommand()
+		cmd.SetArgs(append([]string{"reorder"}, args...))
+		err := cmd.Execute()
+		var exit *exitError
+		switch {
+		case err == nil:
+			return out.String(), errOut.String(), exitOK
+		case errors.As(err, &exit):
+			return out.String(), errOut.String(), exit.code
+		}
+		t.Fatal(err)
+		return "", "", 0
+	}
+
+	out, errOut, code := run("--check", "./...")
+	if code != exitChanges || out != "unsorted.go\n" {
+		t.Errorf("expected unsorted.go to be listed with code %d, got %q with code %d", exitChanges, out, code)
+	}
+	if !strings.Contains(errOut, "2 file(s) processed, 1 to reorder, 0 error(s)") {
+		t.Errorf("unexpected summary %q", errOut)
+	}
+	if content, _ := os.ReadFile("unsorted.go"); string(content) != files["unsorted.go"] {
+		t.Error("the file should not be changed in check mode")
+	}
+
+	if _, _, code := run("--check", "sorted.go"); code != exitOK {
+		t.Errorf("expected code %d, got %d", exitOK, code)
+	}
+
+	if err := os.WriteFile("broken.go", []byte("package p\n\nfunc a( {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, errOut, code = run("--write", "./...")
+	if code != exitErrors {
+		t.Errorf("expected code %d, got %d", exitErrors, code)
+	}
+	if !strings.Contains(errOut, "broken.go:3:9: expected ')', found '{'") {
+		t.Errorf("the error should be printed as file:line:col: message, got %q", errOut)
+	}
+	if !strings.Contains(errOut, "3 file(s) processed, 1 reordered, 1 error(s)") {
+		t.Errorf("unexpected summary %q", errOut)
+	}
+	if content, _ := os.ReadFile("unsorted.go"); string(content) == files["unsorted.go"] {
+		t.Error("the other files should be processed")
+	}
+
+	// a missing file of the list is an error, the other files are processed
+	if err := os.WriteFile("list.txt", []byte("sorted.go\ndeleted.go\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, errOut, code = run("--check", "--files-from", "list.txt")
+	if code != exitErrors || !strings.Contains(errOut, "deleted.go: ") {
+		t.Errorf("expected an error about deleted.go with code %d, got %q with code %d", exitErrors, errOut, code)
+	}
+	if !strings.Contains(errOut, "2 file(s) processed, 0 to reorder, 1 error(s)") {
+		t.Errorf("unexpected summary %q", errOut)
+	}
+}
+
+func TestStdin(t *testing.T) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(currentDir)
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	os.Chdir(tmpDir)
+
+	// the configuration of the directory of --stdin-filename is used
+	os.Mkdir("sub", 0755)
+	if err := os.WriteFile(filepath.Join("sub", ".goreorder"), []byte("order: [func, var]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	const source = "package p\n\nvar x = 1\n\nfunc b() {}\n"
+
+	run := func(args ...string) (string, string, error) {
+		out, errOut := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+		defaultOutpout, defaultErrOutpout = out, errOut
+		stdinInput = strings.NewReader(source)
+		defer func() {
+			defaultOutpout, defaultErrOutpout = bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+			stdinInput = os.Stdin
+		}()
+		cmd := buildMainCommand()
+		cmd.SetArgs(append([]string{"reorder"}, args...))
+		err := cmd.Execute()
+		return out.String(), errOut.String(), err
+	}
+
+	out, _, err := run("-")
+	if err != nil || out != source {
+		t.Errorf("Expected the source unchanged, got %q (%v)", out, err)
+	}
+	out, _, err = run("--stdin-filename", "sub/a.go", "-")
+	if expected := "package p\n\nfunc b() {}\n\nvar x = 1\n"; err != nil || out != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s (%v)", expected, out, err)
+	}
+	if _, err := os.Stat(filepath.Join("sub", "a.go")); err == nil {
+		t.Error("The source read from stdin should not be written")
+	}
+
+	// a test file is given back unchanged
+	out, _, err = run("--stdin-filename", "sub/a_test.go", "-")
+	if err != nil || out != source {
+		t.Errorf("Expected the test file unchanged, got %q (%v)", out, err)
+	}
+
+	if _, _, err := run("-", "a.go"); err == nil {
+		t.Error("Expected an error when - is used with other arguments")
+	}
+	if _, _, err := run(); err == nil {
+		t.Error("Expected an error without arguments")
+	}
+}
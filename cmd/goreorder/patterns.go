@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolvePatterns returns the Go files to process for the command line arguments, in order
+// and without duplicates. An argument can be:
+//   - a Go file,
+//   - a directory, only the Go files of this directory are processed,
+//   - a pattern ending with "...", e.g. "./..." or "./pkg/...", the directory is walked
+//     recursively (vendor, testdata and the directories starting with "." or "_" are skipped),
+//   - an import path of the current module, e.g. "example.com/project/pkg" or
+//     "example.com/project/...".
+//
+// The test files are skipped in directories and patterns.
+func resolvePatterns(args []string) ([]string, error) {
+	files := []string{}
+	seen := map[string]bool{}
+	add := func(file string) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	for _, arg := range args {
+		if arg == "" {
+			return nil, fmt.Errorf("filename is empty")
+		}
+		directory, recursive := strings.CutSuffix(arg, "...")
+		if recursive {
+			directory = strings.TrimSuffix(directory, "/")
+			if directory == "" {
+				directory = "."
+			}
+		}
+		if isImportPath(directory) {
+			resolved, err := resolveImportPath(directory)
+			if err != nil {
+				return nil, err
+			}
+			directory = resolved
+		}
+
+		stat, err := os.Stat(directory)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting file stat: %w", err)
+		}
+		if !stat.IsDir() {
+			if recursive {
+				return nil, fmt.Errorf("%s is not a directory", directory)
+			}
+			add(directory)
+			continue
+		}
+
+		if !recursive {
+			goFiles, err := directoryGoFiles(directory)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range goFiles {
+				add(file)
+			}
+			continue
+		}
+		err = filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return fmt.Errorf("error while walking directory: %w", err)
+			}
+			if !info.IsDir() {
+				return nil
+			}
+			if path != directory && skipDirectory(info.Name()) {
+				return filepath.SkipDir
+			}
+			goFiles, err := directoryGoFiles(path)
+			for _, file := range goFiles {
+				add(file)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// readFileList returns the entries of a list of paths, one per line or separated by NUL
+// characters, e.g. the output of "git diff --name-only" or "git ls-files -z". The empty
+// entries are ignored, and so are the files that are not Go sources or are test files. The
+// missing Go files are kept, they are reported when processed. The entries can also be
+// directories or patterns.
+func readFileList(r io.Reader, nul bool) ([]string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading the file list: %w", err)
+	}
+	separator := []byte("\n")
+	if nul {
+		separator = []byte{0}
+	}
+	entries := []string{}
+	for _, entry := range bytes.Split(content, separator) {
+		path := string(entry)
+		if !nul {
+			path = strings.TrimSpace(path)
+		}
+		if path == "" {
+			continue
+		}
+		if stat, err := os.Stat(path); !strings.HasSuffix(path, "...") && (err != nil || !stat.IsDir()) &&
+			(!strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go")) {
+			log.Println("Skipping " + path + ", not a Go source")
+			continue
+		}
+		entries = append(entries, path)
+	}
+	return entries, nil
+}
+
+// directoryGoFiles returns the Go files of the directory, test files excepted.
+func directoryGoFiles(directory string) ([]string, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		files = append(files, filepath.Join(directory, name))
+	}
+	return files, nil
+}
+
+// isImportPath returns true if the argument is an import path rather than a path on the file
+// system. A path relative to the current directory ("." or starting with "./" or "../"), an
+// absolute path, a Go file or an existing path is local. Otherwise, the argument must look
+// like an import path: its first element has a dot (as in "example.com/project"), or it is a
+// package of the current module. A missing file or directory is then reported as such, not as
+// an unknown package.
+func isImportPath(arg string) bool {
+	if arg == "." || arg == ".." || filepath.IsAbs(arg) || strings.HasSuffix(arg, ".go") ||
+		strings.HasPrefix(arg, "./") || strings.HasPrefix(arg, "../") {
+		return false
+	}
+	if _, err := os.Stat(arg); err == nil {
+		return false
+	}
+	if first, _, _ := strings.Cut(arg, "/"); strings.Contains(first, ".") {
+		return true
+	}
+	_, module, err := findModule()
+	return err == nil && (arg == module || strings.HasPrefix(arg, module+"/"))
+}
+
+// resolveImportPath returns the directory of an import path of the current module.
+func resolveImportPath(importPath string) (string, error) {
+	root, module, err := findModule()
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve %q: %w", importPath, err)
+	}
+	if importPath == module {
+		return root, nil
+	}
+	rel, ok := strings.CutPrefix(importPath, module+"/")
+	if !ok {
+		return "", fmt.Errorf("cannot resolve %q: it is not a package of the module %s", importPath, module)
+	}
+	return filepath.Join(root, filepath.FromSlash(rel)), nil
+}
+
+// findModule returns the root directory and the path of the module of the current directory.
+func findModule() (root, module string, err error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		module, err := modulePath(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			return dir, module, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("go.mod not found")
+		}
+		dir = parent
+	}
+}
+
+// modulePath returns the module path declared in the go.mod file.
+func modulePath(gomod string) (string, error) {
+	file, err := os.Open(gomod)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no module directive in %s", gomod)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolvePatterns(t *testing.T) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(currentDir)
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	os.Chdir(tmpDir)
+
+	files := []string{
+		"go.mod",
+		"main.go",
+		"main_test.go",
+		"pkg/a.go",
+		"pkg/sub/b.go",
+		"pkg/testdata/c.go",
+		"vendor/d.go",
+		".hidden/e.go",
+	}
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := "package p\n"
+		if file == "go.mod" {
+			content = "module example.com/project\n\ngo 1.21\n"
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]struct {
+		args     []string
+		expected []string
+	}{
+		"file":              {[]string{"pkg/a.go"}, []string{"pkg/a.go"}},
+		"directory":         {[]string{"./pkg"}, []string{"pkg/a.go"}},
+		"recursive":         {[]string{"./..."}, []string{"main.go", "pkg/a.go", "pkg/sub/b.go"}},
+		"sub tree":          {[]string{"./pkg/..."}, []string{"pkg/a.go", "pkg/sub/b.go"}},
+		"several":           {[]string{"pkg/sub", "main.go", "pkg/sub/b.go"}, []string{"pkg/sub/b.go", "main.go"}},
+		"import path":       {[]string{"example.com/project/pkg"}, []string{"pkg/a.go"}},
+		"import path tree":  {[]string{"example.com/project/..."}, []string{"main.go", "pkg/a.go", "pkg/sub/b.go"}},
+		"module root":       {[]string{"example.com/project"}, []string{"main.go"}},
+		"explicit test dir": {[]string{"pkg/testdata"}, []string{"pkg/testdata/c.go"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			resolved, err := resolvePatterns(test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, file := range resolved {
+				if rel, err := filepath.Rel(tmpDir, file); err == nil && filepath.IsAbs(file) {
+					resolved[i] = rel
+				}
+				resolved[i] = filepath.ToSlash(resolved[i])
+			}
+			if !reflect.DeepEqual(resolved, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, resolved)
+			}
+		})
+	}
+
+	for _, args := range [][]string{{"other.com/pkg"}, {"./missing"}, {"main.go/..."}} {
+		if _, err := resolvePatterns(args); err == nil {
+			t.Errorf("an error should occur with %v", args)
+		}
+	}
+
+	// a missing path is not taken for an import path
+	for _, arg := range []string{"missing", "pkg/missing", "missing.go", "example.com/project/missing"} {
+		_, err := resolvePatterns([]string{arg})
+		if err == nil || !strings.Contains(err.Error(), "no such file or directory") {
+			t.Errorf("%s should not be found, got %v", arg, err)
+		}
+	}
+}
+
+func TestReadFileList(t *testing.T) {
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(currentDir)
+	tmpDir, err := os.MkdirTemp("", "goreorder-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	os.Chdir(tmpDir)
+	os.Mkdir("pkg", 0755)
+
+	tests := map[string]struct {
+		list     string
+		nul      bool
+		expected []string
+	}{
+		"lines":        {"a.go\n\nb.go\r\n", false, []string{"a.go", "b.go"}},
+		"nul":          {"a.go\x00dir with space/b.go\x00", true, []string{"a.go", "dir with space/b.go"}},
+		"not go":       {"README.md\na.go\na_test.go\ngo.mod\n", false, []string{"a.go"}},
+		"directories":  {"pkg\n./...\n", false, []string{"pkg", "./..."}},
+		"empty":        {"", false, []string{}},
+		"missing file": {"deleted.go\n", false, []string{"deleted.go"}},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			entries, err := readFileList(strings.NewReader(test.list), test.nul)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(entries, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, entries)
+			}
+		})
+	}
+}
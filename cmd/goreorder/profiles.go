@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/viper"
+
+	"github.com/metal3d/goreorder/ordering"
+)
+
+// profiles are the named presets. A profile only gives default values to the configuration
+// keys, each key can be overridden by the configuration file, the environment or the flags.
+var profiles = map[string]map[string]interface{}{
+	// the goreorder defaults
+	"default": {
+		"order":         ordering.DefaultOrder,
+		"reorder-types": false,
+		"method-order":  []string{ordering.MethodName},
+	},
+	// Uber Go style guide: types and their methods before the functions, types are kept
+	// in the order they were written, exported methods first.
+	"uber": {
+		"order":         []string{ordering.Const, ordering.Var, ordering.Interface, ordering.Type, ordering.Func},
+		"reorder-types": false,
+		"method-order":  []string{ordering.MethodExported, ordering.MethodName},
+	},
+	// the "newspaper" rule: entry points first, then what they call
+	"stepdown": {
+		"order":         []string{ordering.Const, ordering.Var, ordering.Interface, ordering.Type, ordering.Init, ordering.Main, ordering.Func},
+		"reorder-types": false,
+		"stepdown":      true,
+	},
+	// mirror the "go doc" output: constants, variables, functions, then types (sorted) with
+	// their consts, vars and constructors
+	"godoc": {
+		"order":         []string{ordering.Const, ordering.Var, ordering.Func, ordering.Interface, ordering.Type},
+		"reorder-types": true,
+		"associate":     true,
+	},
+}
+
+// profileNames returns the sorted names of the profiles.
+func profileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyProfile sets the values of the named profile as viper defaults.
+func applyProfile(v *viper.Viper, name string) error {
+	if name == "" {
+		return nil
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q%s (available profiles are %v)", name, didYouMean(name, profileNames()), profileNames())
+	}
+	for key, value := range profile {
+		v.SetDefault(key, value)
+	}
+	return nil
+}
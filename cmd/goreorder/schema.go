@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	"github.com/metal3d/goreorder/ordering"
+)
+
+const jsonSchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// configEnums are the allowed values for the configuration keys that accept a closed set.
+var configEnums = map[string][]string{
+	"format":       {"gofmt", "goimports"},
+	"order":        allowedOrders(),
+	"profile":      profileNames(),
+	"assertions":   ordering.AssertionPlacements,
+	"method-order": ordering.MethodOrderKeys,
+	"align-arch":   ordering.AlignArchs,
+	"spacing":      ordering.SpacingPolicies,
+}
+
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// schemaProperty is a (small) subset of JSON Schema. It is used to validate the
+// configuration file and to generate the schema for editors.
+type schemaProperty struct {
+	Schema               string                     `json:"$schema,omitempty"`
+	Title                string                     `json:"title,omitempty"`
+	Type                 string                     `json:"type"`
+	Description          string                     `json:"description,omitempty"`
+	Enum                 []string                   `json:"enum,omitempty"`
+	Pattern              string                     `json:"pattern,omitempty"`
+	AnyOf                []*schemaProperty          `json:"anyOf,omitempty"`
+	Items                *schemaProperty            `json:"items,omitempty"`
+	Properties           map[string]*schemaProperty `json:"properties,omitempty"`
+	AdditionalProperties *bool                      `json:"additionalProperties,omitempty"`
+
+	// validate is used in place of the enum check, if it is set
+	validate func(string) error
+}
+
+// keys returns the sorted property names.
+func (s *schemaProperty) keys() []string {
+	keys := make([]string, 0, len(s.Properties))
+	for k := range s.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// allowedOrders returns the values that can be used in the "order" list.
+func allowedOrders() []string {
+	orders := append([]string{ordering.Main, ordering.Init}, ordering.DefaultOrder...)
+	return append(orders, ordering.TypeKinds...)
+}
+
+// buildConfigSchema builds the schema of the configuration file from the ReorderConfig
+// yaml tags. If flags is not nil, the flag usages are used as descriptions.
+func buildConfigSchema(flags *pflag.FlagSet) *schemaProperty {
+	noAdditional := false
+	root := &schemaProperty{
+		Schema:               jsonSchemaVersion,
+		Title:                "goreorder configuration",
+		Type:                 "object",
+		Properties:           map[string]*schemaProperty{},
+		AdditionalProperties: &noAdditional,
+	}
+
+	t := reflect.TypeOf(ReorderConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		prop := schemaForType(field.Type)
+		if flags != nil {
+			if f := flags.Lookup(name); f != nil {
+				prop.Description = f.Usage
+			}
+		}
+		if enum, ok := configEnums[name]; ok {
+			if prop.Items != nil {
+				prop.Items.Enum = enum
+			} else {
+				prop.Enum = enum
+			}
+		}
+		if name == "order" {
+			// a kind, or a kind with a name pattern
+			prop.Items = &schemaProperty{
+				Type: "string",
+				AnyOf: []*schemaProperty{
+					{Type: "string", Enum: configEnums[name]},
+					{Type: "string", Pattern: "^(" + strings.Join(ordering.PatternKinds, "|") + "):.+$"},
+				},
+				validate: validateOrderEntry,
+			}
+		}
+		if name == "priority" {
+			// a list of names per kind
+			prop.Properties = map[string]*schemaProperty{}
+			prop.AdditionalProperties = &noAdditional
+			for _, kind := range ordering.PriorityKinds {
+				prop.Properties[kind] = schemaForType(field.Type.Elem())
+			}
+		}
+		root.Properties[name] = prop
+	}
+	return root
+}
+
+func schemaForType(t reflect.Type) *schemaProperty {
+	switch t.Kind() {
+	case reflect.Bool:
+		return &schemaProperty{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &schemaProperty{Type: "integer"}
+	case reflect.Slice:
+		return &schemaProperty{Type: "array", Items: schemaForType(t.Elem())}
+	case reflect.Map:
+		return &schemaProperty{Type: "object"}
+	default:
+		return &schemaProperty{Type: "string"}
+	}
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// suggest returns the closest candidate to word, or an empty string if none is close enough.
+func suggest(word string, candidates []string) string {
+	best := ""
+	bestDistance := max(2, len(word)/3) + 1
+	for _, candidate := range candidates {
+		d := levenshtein(strings.ToLower(word), strings.ToLower(candidate))
+		if d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// didYouMean returns a " did you mean ...?" hint, or an empty string.
+func didYouMean(word string, candidates []string) string {
+	if s := suggest(word, candidates); s != "" {
+		return fmt.Sprintf(", did you mean %q?", s)
+	}
+	return ""
+}
+
+// validateConfig checks the values of the configuration, whatever they come from.
+func validateConfig(config *ReorderConfig) error {
+	var errs []error
+	for _, v := range config.DefOrder {
+		if err := validateOrderEntry(v); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if !contains(configEnums["format"], config.FormatToolName) {
+		errs = append(errs, fmt.Errorf(
+			"invalid format %q%s (only gofmt or goimports are allowed)",
+			config.FormatToolName, didYouMean(config.FormatToolName, configEnums["format"])))
+	}
+	for kind := range config.Priority {
+		if !contains(ordering.PriorityKinds, kind) {
+			errs = append(errs, fmt.Errorf(
+				"invalid priority kind %q%s (allowed kinds are %s)",
+				kind, didYouMean(kind, ordering.PriorityKinds), strings.Join(ordering.PriorityKinds, ", ")))
+		}
+	}
+	if config.Assertions != "" && !contains(configEnums["assertions"], config.Assertions) {
+		errs = append(errs, fmt.Errorf(
+			"invalid assertions placement %q%s (allowed values are %s)",
+			config.Assertions, didYouMean(config.Assertions, ordering.AssertionPlacements), strings.Join(ordering.AssertionPlacements, ", ")))
+	}
+	for _, key := range config.MethodOrder {
+		if !contains(configEnums["method-order"], key) {
+			errs = append(errs, fmt.Errorf(
+				"invalid method order %q%s (allowed values are %s)",
+				key, didYouMean(key, ordering.MethodOrderKeys), strings.Join(ordering.MethodOrderKeys, ", ")))
+		}
+	}
+	if config.AlignArch != "" && !contains(configEnums["align-arch"], config.AlignArch) {
+		errs = append(errs, fmt.Errorf(
+			"invalid architecture %q%s (allowed values are %s)",
+			config.AlignArch, didYouMean(config.AlignArch, ordering.AlignArchs), strings.Join(ordering.AlignArchs, ", ")))
+	}
+	if config.Spacing != "" && !contains(configEnums["spacing"], config.Spacing) {
+		errs = append(errs, fmt.Errorf(
+			"invalid spacing %q%s (allowed values are %s)",
+			config.Spacing, didYouMean(config.Spacing, ordering.SpacingPolicies), strings.Join(ordering.SpacingPolicies, ", ")))
+	}
+	if config.Banners {
+		if _, err := ordering.ParseBannerTemplate(config.BannerTemplate); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if config.Profile != "" && !contains(configEnums["profile"], config.Profile) {
+		errs = append(errs, fmt.Errorf(
+			"unknown profile %q%s (available profiles are %s)",
+			config.Profile, didYouMean(config.Profile, configEnums["profile"]), strings.Join(configEnums["profile"], ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// validateOrderEntry checks an entry of the order list, a kind or a kind with a name pattern.
+func validateOrderEntry(entry string) error {
+	kind, _, hasPattern := strings.Cut(entry, ":")
+	allowed := configEnums["order"]
+	if hasPattern {
+		allowed = ordering.PatternKinds
+	}
+	if !contains(allowed, kind) {
+		return fmt.Errorf(
+			"invalid order name %q%s (allowed values are %s)",
+			kind, didYouMean(kind, allowed), strings.Join(allowed, ", "))
+	}
+	_, _, err := ordering.ParseOrder(entry)
+	return err
+}
+
+// validateConfigFile reads the given YAML configuration file and checks it against the
+// schema. Errors are prefixed by the position in the file.
+func validateConfigFile(filename string, schema *schemaProperty) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+			return fmt.Errorf("%s:%s: %s", filename, m[1], m[2])
+		}
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil // empty file
+	}
+	return errors.Join(validateNode(filename, "", doc.Content[0], schema)...)
+}
+
+func validateNode(filename, key string, node *yaml.Node, schema *schemaProperty) (errs []error) {
+	errorf := func(n *yaml.Node, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s:%d:%d: %s", filename, n.Line, n.Column, fmt.Sprintf(format, args...)))
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch schema.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			errorf(node, "%s should be a mapping", describeKey(key))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			prop, ok := schema.Properties[k.Value]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					errorf(k, "unknown key %q%s", k.Value, didYouMean(k.Value, schema.keys()))
+				}
+				continue
+			}
+			errs = append(errs, validateNode(filename, k.Value, v, prop)...)
+		}
+	case "array":
+		switch {
+		case node.Kind == yaml.SequenceNode:
+			for _, item := range node.Content {
+				errs = append(errs, validateNode(filename, key, item, schema.Items)...)
+			}
+		case node.Kind == yaml.ScalarNode && node.Tag == "!!str":
+			// comma separated list, as for the command line
+			for _, v := range splitList(node.Value) {
+				item := *node
+				item.Value = v
+				errs = append(errs, validateNode(filename, key, &item, schema.Items)...)
+			}
+		default:
+			errorf(node, "%s should be a list", describeKey(key))
+		}
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			errorf(node, "%s should be a boolean (true or false), got %q", describeKey(key), node.Value)
+		}
+	case "integer":
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			errorf(node, "%s should be an integer, got %q", describeKey(key), node.Value)
+		}
+	default:
+		if node.Kind != yaml.ScalarNode {
+			errorf(node, "%s should be a string", describeKey(key))
+			return
+		}
+		if schema.validate != nil {
+			if err := schema.validate(node.Value); err != nil {
+				errorf(node, "%v", err)
+			}
+			return
+		}
+		if len(schema.Enum) > 0 && !contains(schema.Enum, node.Value) {
+			errorf(node, "invalid value %q for %s%s (allowed values are %s)",
+				node.Value, describeKey(key), didYouMean(node.Value, schema.Enum), strings.Join(schema.Enum, ", "))
+		}
+	}
+	return
+}
+
+func describeKey(key string) string {
+	if key == "" {
+		return "configuration"
+	}
+	return fmt.Sprintf("%q", key)
+}
+
+// splitList splits a comma or space separated list.
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func printConfigSchema(flags *pflag.FlagSet) error {
+	enc := json.NewEncoder(defaultOutpout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(buildConfigSchema(flags))
+}
@@ -0,0 +1,301 @@
+package ordering
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NoAlignDirective is the comment that prevents the alignment of a struct.
+const NoAlignDirective = "//goreorder:noalign"
+
+// DefaultAlignArch is the architecture used to compute the sizes if none is given.
+const DefaultAlignArch = "amd64"
+
+// AlignArchs are the architectures (GOARCH) known to compute the sizes of the types.
+var AlignArchs = []string{
+	"386", "amd64", "arm", "arm64", "loong64", "mips", "mipsle", "mips64", "mips64le",
+	"ppc64", "ppc64le", "riscv64", "s390x", "sparc64", "wasm",
+}
+
+// layoutTagKeys are the struct tag keys of the binary encoding packages, the structs having
+// such a tag depend on the order of the fields.
+var layoutTagKeys = []string{"binary", "struc", "restruct"}
+
+// alignedField is a field of a struct (possibly with several names) with its lines.
+type alignedField struct {
+	fieldChunk
+	size, align int64
+}
+
+// alignStructs reorders the fields of the struct types declared at the top level to
+// minimize the padding, as the fieldalignment analyzer does, with the gc sizes of the given
+// architecture. A struct is only changed if its size is reduced. The fields end up sorted by
+// alignment, the zero-sized fields first, and they keep their relative order otherwise.
+//
+// The structs are not changed if the file uses cgo, if they are annotated with the
+// NoAlignDirective, have a blank (padding) field or a tag of a binary encoding package, or
+// are built with unkeyed literals in the file.
+func alignStructs(filename string, content []byte, arch string) ([]byte, error) {
+	if arch == "" {
+		arch = DefaultAlignArch
+	}
+	sizes := types.SizesFor("gc", arch)
+	if sizes == nil {
+		return nil, fmt.Errorf("unknown architecture %q, allowed values are %s", arch, strings.Join(AlignArchs, ", "))
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
+	if err != nil {
+		return nil, newParseError(filename, err)
+	}
+	for _, imp := range f.Imports {
+		if imp.Path.Value == `"C"` {
+			return content, nil
+		}
+	}
+
+	// the imported types are resolved when possible, the structs with type errors are skipped
+	var typeErrors []token.Pos
+	info := &types.Info{Defs: map[*ast.Ident]types.Object{}}
+	conf := types.Config{
+		Importer: importer.Default(),
+		Sizes:    sizes,
+		Error: func(err error) {
+			if e, ok := err.(types.Error); ok {
+				typeErrors = append(typeErrors, e.Pos)
+			}
+		},
+	}
+	conf.Check(f.Name.Name, fset, []*ast.File{f}, info)
+
+	unkeyed := unkeyedLiteralTypes(f)
+	lines := strings.Split(string(content), "\n")
+	type edit struct {
+		start, end int
+		lines      []string
+	}
+	edits := []edit{}
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.TYPE || hasDirective(d.Doc, NoAlignDirective) {
+			continue
+		}
+		for _, spec := range d.Specs {
+			s, ok := spec.(*ast.TypeSpec)
+			if !ok || unkeyed[s.Name.Name] || hasDirective(s.Doc, NoAlignDirective) || hasDirective(s.Comment, NoAlignDirective) {
+				continue
+			}
+			st, ok := s.Type.(*ast.StructType)
+			if !ok || hasErrorIn(typeErrors, st) {
+				continue
+			}
+			obj, ok := info.Defs[s.Name]
+			if !ok || obj == nil {
+				continue
+			}
+			structType, ok := obj.Type().Underlying().(*types.Struct)
+			if !ok || !validType(structType, map[types.Type]bool{}) {
+				continue
+			}
+			fields := alignableFields(fset, lines, st, structType, sizes)
+			if fields == nil {
+				continue
+			}
+			sortByAlignment(fields)
+			if structSize(fields, sizes.Alignof(structType)) >= sizes.Sizeof(structType) {
+				continue
+			}
+			rewritten := []string{}
+			for _, field := range fields {
+				rewritten = append(rewritten, lines[field.start-1:field.end]...)
+			}
+			first, last := fieldsRange(fset, st)
+			edits = append(edits, edit{start: first, end: last, lines: rewritten})
+		}
+	}
+
+	// from the bottom, so the line numbers of the next edits stay valid
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		lines = append(lines[:e.start-1], append(e.lines, lines[e.end:]...)...)
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// alignableFields returns the fields of the struct with their size and alignment, or nil if
+// the fields cannot be moved: several fields on a line, comments between the fields, blank
+// fields or layout tags.
+func alignableFields(fset *token.FileSet, lines []string, st *ast.StructType, structType *types.Struct, sizes types.Sizes) []alignedField {
+	line := func(p token.Pos) int {
+		return fset.Position(p).Line
+	}
+	if st.Fields == nil || len(st.Fields.List) < 2 {
+		return nil
+	}
+
+	fields := []alignedField{}
+	previous := line(st.Fields.Opening)
+	index := 0 // index of the field in structType
+	for _, field := range st.Fields.List {
+		chunk := fieldChunk{start: line(field.Pos()), end: line(field.End())}
+		if field.Doc != nil {
+			chunk.start = line(field.Doc.Pos())
+		}
+		if field.Comment != nil {
+			chunk.end = max(chunk.end, line(field.Comment.End()))
+		}
+		if chunk.start <= previous {
+			return nil
+		}
+		// only blank lines between the fields, they are removed
+		for _, l := range lines[previous : chunk.start-1] {
+			if strings.TrimSpace(l) != "" {
+				return nil
+			}
+		}
+		if field.Tag != nil && hasLayoutTag(field.Tag.Value) {
+			return nil
+		}
+
+		count := max(1, len(field.Names))
+		for _, name := range field.Names {
+			if name.Name == "_" {
+				return nil
+			}
+		}
+		t := structType.Field(index).Type()
+		fields = append(fields, alignedField{
+			fieldChunk: chunk,
+			size:       int64(count) * sizes.Sizeof(t),
+			align:      sizes.Alignof(t),
+		})
+		index += count
+		previous = chunk.end
+	}
+	if previous >= line(st.Fields.Closing) {
+		return nil
+	}
+	return fields
+}
+
+// sortByAlignment sorts the fields in the order that minimizes the padding: the zero-sized
+// fields first, then by decreasing alignment.
+func sortByAlignment(fields []alignedField) {
+	sort.SliceStable(fields, func(i, j int) bool {
+		if (fields[i].size == 0) != (fields[j].size == 0) {
+			return fields[i].size == 0
+		}
+		return fields[i].align > fields[j].align
+	})
+}
+
+// structSize returns the size of a struct having the fields in the given order.
+func structSize(fields []alignedField, structAlign int64) int64 {
+	offset := int64(0)
+	for _, field := range fields {
+		offset = alignTo(offset, field.align) + field.size
+	}
+	// gc pads a struct ending with a zero-sized field
+	if offset > 0 && fields[len(fields)-1].size == 0 {
+		offset++
+	}
+	return alignTo(offset, structAlign)
+}
+
+func alignTo(offset, align int64) int64 {
+	if align <= 1 {
+		return offset
+	}
+	return (offset + align - 1) / align * align
+}
+
+// fieldsRange returns the first and last lines of the fields of the struct.
+func fieldsRange(fset *token.FileSet, st *ast.StructType) (first, last int) {
+	list := st.Fields.List
+	first = fset.Position(list[0].Pos()).Line
+	if list[0].Doc != nil {
+		first = fset.Position(list[0].Doc.Pos()).Line
+	}
+	lastField := list[len(list)-1]
+	last = fset.Position(lastField.End()).Line
+	if lastField.Comment != nil {
+		last = max(last, fset.Position(lastField.Comment.End()).Line)
+	}
+	return first, last
+}
+
+// hasDirective returns true if the comment group contains the directive.
+func hasDirective(doc *ast.CommentGroup, directive string) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+// hasErrorIn returns true if one of the error positions is in the node.
+func hasErrorIn(positions []token.Pos, node ast.Node) bool {
+	for _, pos := range positions {
+		if pos >= node.Pos() && pos < node.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLayoutTag returns true if the (quoted) tag has a key of a binary encoding package.
+func hasLayoutTag(literal string) bool {
+	value, err := strconv.Unquote(literal)
+	if err != nil {
+		return false
+	}
+	tag := reflect.StructTag(value)
+	for _, key := range layoutTagKeys {
+		if _, ok := tag.Lookup(key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validType returns false if the type contains an invalid type, e.g. from a package that
+// cannot be imported.
+func validType(t types.Type, seen map[types.Type]bool) bool {
+	if seen[t] {
+		return true
+	}
+	seen[t] = true
+	switch t := t.(type) {
+	case *types.Basic:
+		return t.Kind() != types.Invalid
+	case *types.Array:
+		return validType(t.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if !validType(t.Field(i).Type(), seen) {
+				return false
+			}
+		}
+	case *types.TypeParam:
+		return false // the size depends on the instantiation
+	default:
+		// named types and aliases
+		if u := t.Underlying(); u != t {
+			return validType(u, seen)
+		}
+	}
+	return true
+}
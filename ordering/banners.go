@@ -0,0 +1,155 @@
+package ordering
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"text/template"
+)
+
+// DefaultBannerTemplate is the template of the section banners.
+const DefaultBannerTemplate = "// {{.Title}}"
+
+// SectionTitles are the titles of the sections, used in the banners.
+var SectionTitles = map[Order]string{
+	Const:     "Constants",
+	Var:       "Variables",
+	Interface: "Interfaces",
+	Type:      "Types",
+	Func:      "Functions",
+	Init:      "Initialization",
+	Main:      "Main",
+	Struct:    "Structs",
+	Alias:     "Aliases",
+	FuncType:  "Function types",
+	Basic:     "Basic types",
+}
+
+// bannerData is given to the banner template.
+type bannerData struct {
+	Kind    Order  // e.g. "type"
+	Pattern string // the name pattern of the order entry, if any
+	Title   string // e.g. "Types", or "Types *Error" with a pattern
+}
+
+// ParseBannerTemplate parses a banner template (text/template syntax). The template receives
+// the Kind, Pattern and Title of the section, and it must render comment lines only.
+func ParseBannerTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("banner").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid banner template: %w", err)
+	}
+	lines, err := renderBanner(tmpl, &orderRule{kind: Type, pattern: "*"})
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "//") {
+			return nil, fmt.Errorf("invalid banner template: %q is not a line comment", line)
+		}
+	}
+	return tmpl, nil
+}
+
+// renderBanner returns the lines of the banner of an order entry.
+func renderBanner(tmpl *template.Template, rule *orderRule) ([]string, error) {
+	data := bannerData{Kind: rule.kind, Pattern: rule.pattern, Title: SectionTitles[rule.kind]}
+	if data.Title == "" {
+		data.Title = rule.kind
+	}
+	if rule.pattern != "" {
+		data.Title += " " + rule.pattern
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("invalid banner template: %w", err)
+	}
+	return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n"), nil
+}
+
+// stripBanners removes the banners that goreorder could have written for the given rules, or
+// for any kind, so they are not taken as comments of the declarations on re-runs. Only the
+// free-floating comments between the top level declarations are removed: the doc comments,
+// the comments inside the declarations and the strings are kept, even if they match a banner.
+func stripBanners(content []byte, tmpl *template.Template, rules []*orderRule) ([]byte, error) {
+	candidates := append([]*orderRule{}, rules...)
+	for kind := range SectionTitles {
+		candidates = append(candidates, &orderRule{kind: kind})
+	}
+	banners := make([][]string, 0, len(candidates))
+	for _, rule := range candidates {
+		banner, err := renderBanner(tmpl, rule)
+		if err != nil {
+			return nil, err
+		}
+		banners = append(banners, banner)
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		// reported by the parsing of the source
+		return content, nil
+	}
+	docs := map[*ast.CommentGroup]bool{f.Doc: true}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			docs[d.Doc] = true
+		case *ast.GenDecl:
+			docs[d.Doc] = true
+		}
+	}
+
+	lines := strings.Split(string(content), "\n")
+	removed := map[int]bool{} // 0-based line numbers
+	for _, group := range f.Comments {
+		if docs[group] || group.Pos() < f.Name.End() || insideDeclaration(f.Decls, group) {
+			continue
+		}
+		first, last := fset.Position(group.Pos()).Line, fset.Position(group.End()).Line
+		for _, banner := range banners {
+			if len(banner) == last-first+1 && matchLines(lines[first-1:last], banner) {
+				for i := first - 1; i < last; i++ {
+					removed[i] = true
+				}
+				break
+			}
+		}
+	}
+
+	kept := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if !removed[i] {
+			kept = append(kept, line)
+		}
+	}
+	return []byte(strings.Join(kept, "\n")), nil
+}
+
+// insideDeclaration returns true if the comment group is inside one of the declarations.
+func insideDeclaration(decls []ast.Decl, group *ast.CommentGroup) bool {
+	for _, decl := range decls {
+		if group.Pos() >= decl.Pos() && group.End() <= decl.End() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLines returns true if lines starts with the expected lines, the trailing spaces being
+// ignored.
+func matchLines(lines, expected []string) bool {
+	if len(lines) < len(expected) {
+		return false
+	}
+	for i, line := range expected {
+		if strings.TrimRight(lines[i], " \t") != line {
+			return false
+		}
+	}
+	return true
+}
@@ -1,7 +1,7 @@
 package ordering
 
 import (
-	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -18,7 +18,7 @@ func doDiff(content, newcontent []byte, filename string) (string, error) {
 	// create a and b directories in temporary directory
 	tmpDir, err := os.MkdirTemp("", "")
 	if err != nil {
-		return string(content), errors.New("Failed to create temp directory: " + err.Error())
+		return string(content), &DiffError{Err: fmt.Errorf("failed to create temp directory: %w", err)}
 	}
 	defer os.RemoveAll(tmpDir) // clean up
 
@@ -34,21 +34,21 @@ func doDiff(content, newcontent []byte, filename string) (string, error) {
 
 	fba := filepath.Join(dirA, filebase)
 	if err := os.WriteFile(fba, content, 0644); err != nil {
-		return string(content), errors.New("Failed to write to temporary file: " + err.Error())
+		return string(content), &DiffError{Err: fmt.Errorf("failed to write to temporary file: %w", err)}
 	}
 	fbb := filepath.Join(dirB, filebase)
 	if err := os.WriteFile(fbb, newcontent, 0644); err != nil {
-		return string(content), errors.New("Failed to write to temporary file: " + err.Error())
+		return string(content), &DiffError{Err: fmt.Errorf("failed to write to temporary file: %w", err)}
 	}
 	// run diff -Naur a b
 	cmd := exec.Command("diff", "-Naur", dirA, dirB)
 	out, err := cmd.CombinedOutput()
-	if cmd.ProcessState.ExitCode() <= 1 { // 1 is valid, it means there are differences, 0 means no differences
+	if cmd.ProcessState != nil && cmd.ProcessState.ExitCode() <= 1 { // 1 is valid, it means there are differences, 0 means no differences
 		// remplace tmp/a/ and tmp/b/ with a/ and b/ in the diff output
 		// to make it more readable and easier to apply with patch -p1
 		out := strings.ReplaceAll(string(out), tmpDir+"/a/", "a/")
 		out = strings.ReplaceAll(out, tmpDir+"/b/", "b/")
 		return out, nil
 	}
-	return string(out), err
+	return string(content), &DiffError{Output: string(out), Err: err}
 }
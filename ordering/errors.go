@@ -0,0 +1,101 @@
+package ordering
+
+import (
+	"errors"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// ParseError is returned when the source cannot be parsed.
+type ParseError struct {
+	Pos token.Position // position of the first error
+	Msg string
+	Err error // the underlying error, a scanner.ErrorList for syntax errors
+}
+
+// newParseError returns a ParseError for the error of the Go parser.
+func newParseError(filename string, err error) *ParseError {
+	e := &ParseError{Pos: token.Position{Filename: filename}, Msg: err.Error(), Err: err}
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		e.Pos, e.Msg = list[0].Pos, list[0].Msg
+	}
+	return e
+}
+
+func (e *ParseError) Error() string {
+	if e.Pos.IsValid() || e.Pos.Filename != "" {
+		return e.Pos.String() + ": " + e.Msg
+	}
+	return e.Msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// FormatError is returned when the reordered source cannot be formatted.
+type FormatError struct {
+	Command string // "gofmt" or the format command
+	Stderr  string // the error output of the format command, if any
+	Err     error
+}
+
+func (e *FormatError) Error() string {
+	msg := "failed to format source with " + e.Command + ": " + e.Err.Error()
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		msg += "\n" + stderr
+	}
+	return msg
+}
+
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// DiffError is returned when the diff between the source and the reordered source cannot be
+// made.
+type DiffError struct {
+	Output string // the output of the diff command, if any
+	Err    error
+}
+
+func (e *DiffError) Error() string {
+	msg := "failed to make the diff: " + e.Err.Error()
+	if output := strings.TrimSpace(e.Output); output != "" {
+		msg += "\n" + output
+	}
+	return msg
+}
+
+func (e *DiffError) Unwrap() error {
+	return e.Err
+}
+
+// VerificationError is returned when the reordered source does not declare the same
+// elements as the source. The source is not changed in that case.
+type VerificationError struct {
+	Missing    []string // declarations of the source not found in the result, e.g. "func (T) String"
+	Unexpected []string // declarations of the result not found in the source
+	Err        error    // the parse error of the result, if any
+}
+
+func (e *VerificationError) Error() string {
+	if e.Err != nil {
+		return "the reordered source is invalid: " + e.Err.Error()
+	}
+	parts := []string{}
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unexpected) > 0 {
+		parts = append(parts, "unexpected "+strings.Join(e.Unexpected, ", "))
+	}
+	return fmt.Sprintf("the reordered source does not declare the same elements: %s", strings.Join(parts, "; "))
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
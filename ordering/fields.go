@@ -0,0 +1,197 @@
+package ordering
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// fieldChunk is a field of a struct, or a method of an interface, with its doc comment.
+type fieldChunk struct {
+	start, end int // lines, 1-based and inclusive
+	embedded   bool
+	name       string
+}
+
+// sortDeclarationFields sorts the fields of the struct types and the methods of the
+// interfaces declared at the top level of the source. The fields are sorted in each group
+// (fields separated by blank lines or comments), the embedded fields first. The doc
+// comments, trailing comments and tags are kept with their field, and the number of lines
+// is not changed.
+//
+// The struct types that are built with unkeyed composite literals (T{1, "a"}) in the file
+// are not changed, as the literals depend on the order of the fields.
+func sortDeclarationFields(filename string, content []byte, structs, interfaces bool) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, content, parser.ParseComments)
+	if err != nil {
+		return nil, newParseError(filename, err)
+	}
+	unkeyed := unkeyedLiteralTypes(f)
+
+	lines := strings.Split(string(content), "\n")
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range d.Specs {
+			s, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			switch t := s.Type.(type) {
+			case *ast.StructType:
+				if structs && !unkeyed[s.Name.Name] {
+					sortFieldList(lines, fset, t.Fields)
+				}
+			case *ast.InterfaceType:
+				if interfaces {
+					sortFieldList(lines, fset, t.Methods)
+				}
+			}
+		}
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// sortFieldList sorts, in place, the lines of each group of fields of the list. Nothing is
+// done if several fields share a line, e.g. "struct{ a, b int }" or "a int; b int".
+func sortFieldList(lines []string, fset *token.FileSet, list *ast.FieldList) {
+	if list == nil || len(list.List) < 2 {
+		return
+	}
+	line := func(p token.Pos) int {
+		return fset.Position(p).Line
+	}
+
+	chunks := make([]fieldChunk, 0, len(list.List))
+	previous := line(list.Opening)
+	for _, field := range list.List {
+		chunk := fieldChunk{start: line(field.Pos()), end: line(field.End())}
+		if field.Doc != nil {
+			chunk.start = line(field.Doc.Pos())
+		}
+		if field.Comment != nil {
+			chunk.end = max(chunk.end, line(field.Comment.End()))
+		}
+		if chunk.start <= previous {
+			return
+		}
+		if len(field.Names) > 0 {
+			chunk.name = field.Names[0].Name
+		} else {
+			chunk.embedded = true
+			chunk.name = embeddedName(field.Type)
+		}
+		chunks = append(chunks, chunk)
+		previous = chunk.end
+	}
+	if previous >= line(list.Closing) {
+		return
+	}
+
+	// groups are the runs of fields without any line between them
+	for first := 0; first < len(chunks); {
+		last := first
+		for last+1 < len(chunks) && chunks[last+1].start == chunks[last].end+1 {
+			last++
+		}
+		sortChunks(lines, chunks[first:last+1])
+		first = last + 1
+	}
+}
+
+// sortChunks sorts a group of consecutive chunks, embedded first, then by name.
+func sortChunks(lines []string, group []fieldChunk) {
+	sorted := append([]fieldChunk{}, group...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].embedded != sorted[j].embedded {
+			return sorted[i].embedded
+		}
+		return sorted[i].name < sorted[j].name
+	})
+
+	start, end := group[0].start, group[len(group)-1].end
+	rewritten := make([]string, 0, end-start+1)
+	for _, chunk := range sorted {
+		rewritten = append(rewritten, lines[chunk.start-1:chunk.end]...)
+	}
+	copy(lines[start-1:end], rewritten)
+}
+
+// embeddedName returns the name used to sort an embedded field or interface ("Mutex" for
+// *sync.Mutex, "Reader" for io.Reader). Type constraints (~int | string) have no name.
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(t.X)
+	case *ast.IndexListExpr:
+		return embeddedName(t.X)
+	}
+	return ""
+}
+
+// unkeyedLiteralTypes returns the local type names used in composite literals with unkeyed
+// elements, including the literals with an elided type ([]T{{1, "a"}}).
+func unkeyedLiteralTypes(f *ast.File) map[string]bool {
+	types := map[string]bool{}
+	elided := map[*ast.CompositeLit]ast.Expr{} // type of the literals with an elided type
+	ast.Inspect(f, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		litType := lit.Type
+		if litType == nil {
+			litType = elided[lit]
+		}
+
+		// the elements of arrays, slices and maps can omit their type
+		var elemType, keyType ast.Expr
+		switch t := litType.(type) {
+		case *ast.ArrayType:
+			elemType = t.Elt
+		case *ast.MapType:
+			keyType, elemType = t.Key, t.Value
+		}
+		for _, elt := range lit.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				if key, ok := kv.Key.(*ast.CompositeLit); ok && key.Type == nil {
+					elided[key] = keyType
+				}
+				elt = kv.Value
+			}
+			if unary, ok := elt.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+				elt = unary.X
+			}
+			if value, ok := elt.(*ast.CompositeLit); ok && value.Type == nil {
+				if star, ok := elemType.(*ast.StarExpr); ok {
+					elided[value] = star.X
+				} else {
+					elided[value] = elemType
+				}
+			}
+		}
+
+		if len(lit.Elts) == 0 {
+			return true
+		}
+		if _, keyed := lit.Elts[0].(*ast.KeyValueExpr); keyed {
+			return true
+		}
+		if name := baseTypeName(litType); name != "" {
+			types[name] = true
+		}
+		return true
+	})
+	return types
+}
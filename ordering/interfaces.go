@@ -0,0 +1,168 @@
+package ordering
+
+import "sort"
+
+// MethodSet is the list of methods of an interface, in declaration order.
+type MethodSet struct {
+	Name    string
+	Methods []string
+
+	// Embeds are the embedded interfaces, their methods are placed before Methods.
+	Embeds []string
+}
+
+// KnownInterfaces are the interfaces of the standard library that types commonly implement.
+var KnownInterfaces = []MethodSet{
+	{Name: "sort.Interface", Methods: []string{"Len", "Less", "Swap"}},
+	{Name: "heap.Interface", Methods: []string{"Push", "Pop"}, Embeds: []string{"sort.Interface"}},
+	{Name: "io.Reader", Methods: []string{"Read"}},
+	{Name: "io.Writer", Methods: []string{"Write"}},
+	{Name: "io.Closer", Methods: []string{"Close"}},
+	{Name: "io.Seeker", Methods: []string{"Seek"}},
+	{Name: "io.ReadWriter", Embeds: []string{"io.Reader", "io.Writer"}},
+	{Name: "io.ReadCloser", Embeds: []string{"io.Reader", "io.Closer"}},
+	{Name: "io.WriteCloser", Embeds: []string{"io.Writer", "io.Closer"}},
+	{Name: "io.ReadWriteCloser", Embeds: []string{"io.Reader", "io.Writer", "io.Closer"}},
+	{Name: "io.ReaderAt", Methods: []string{"ReadAt"}},
+	{Name: "io.WriterAt", Methods: []string{"WriteAt"}},
+	{Name: "io.ReaderFrom", Methods: []string{"ReadFrom"}},
+	{Name: "io.WriterTo", Methods: []string{"WriteTo"}},
+	{Name: "io.ByteReader", Methods: []string{"ReadByte"}},
+	{Name: "io.RuneReader", Methods: []string{"ReadRune"}},
+	{Name: "io.StringWriter", Methods: []string{"WriteString"}},
+	{Name: "fmt.Stringer", Methods: []string{"String"}},
+	{Name: "fmt.GoStringer", Methods: []string{"GoString"}},
+	{Name: "fmt.Formatter", Methods: []string{"Format"}},
+	{Name: "error", Methods: []string{"Error"}},
+	{Name: "flag.Value", Methods: []string{"String", "Set"}},
+	{Name: "context.Context", Methods: []string{"Deadline", "Done", "Err", "Value"}},
+	{Name: "http.Handler", Methods: []string{"ServeHTTP"}},
+	{Name: "json.Marshaler", Methods: []string{"MarshalJSON"}},
+	{Name: "json.Unmarshaler", Methods: []string{"UnmarshalJSON"}},
+	{Name: "encoding.TextMarshaler", Methods: []string{"MarshalText"}},
+	{Name: "encoding.TextUnmarshaler", Methods: []string{"UnmarshalText"}},
+	{Name: "encoding.BinaryMarshaler", Methods: []string{"MarshalBinary"}},
+	{Name: "encoding.BinaryUnmarshaler", Methods: []string{"UnmarshalBinary"}},
+	{Name: "sql.Scanner", Methods: []string{"Scan"}},
+	{Name: "driver.Valuer", Methods: []string{"Value"}},
+}
+
+// MethodSets returns the method sets of the interfaces declared in the parsed file, in
+// declaration order.
+func (info *ParsedInfo) MethodSets() []MethodSet {
+	names := append([]string{}, *info.InterfaceNames...)
+	sort.SliceStable(names, func(i, j int) bool {
+		return info.Interfaces[names[i]].OpeningLine < info.Interfaces[names[j]].OpeningLine
+	})
+	sets := make([]MethodSet, 0, len(names))
+	for _, name := range names {
+		sets = append(sets, MethodSet{
+			Name:    name,
+			Methods: info.Interfaces[name].InterfaceMethods,
+			Embeds:  info.Interfaces[name].InterfaceEmbeds,
+		})
+	}
+	return sets
+}
+
+// groupByInterface reorders the methods of each type so that the methods satisfying an
+// interface are grouped in the interface declaration order, the interfaces being taken in
+// the order of sets. The other methods are placed after, in their current order. When a
+// method belongs to several implemented interfaces, the largest interface takes it.
+func groupByInterface(methods map[string][]*GoType, sets []MethodSet) {
+	sets = resolveMethodSets(sets)
+	bySize := make([]int, len(sets))
+	for i := range bySize {
+		bySize[i] = i
+	}
+	sort.SliceStable(bySize, func(i, j int) bool {
+		return len(sets[bySize[i]].Methods) > len(sets[bySize[j]].Methods)
+	})
+
+	for typename, list := range methods {
+		byName := make(map[string]*GoType, len(list))
+		for _, method := range list {
+			byName[method.Name] = method
+		}
+
+		// interface (index in sets) of each method
+		owners := map[string]int{}
+		for _, i := range bySize {
+			if !implements(byName, sets[i].Methods) {
+				continue
+			}
+			for _, name := range sets[i].Methods {
+				if _, ok := owners[name]; !ok {
+					owners[name] = i
+				}
+			}
+		}
+
+		sorted := make([]*GoType, 0, len(list))
+		for i, set := range sets {
+			for _, name := range set.Methods {
+				if owner, ok := owners[name]; ok && owner == i {
+					sorted = append(sorted, byName[name])
+				}
+			}
+		}
+		for _, method := range list {
+			if _, ok := owners[method.Name]; !ok {
+				sorted = append(sorted, method)
+			}
+		}
+		methods[typename] = sorted
+	}
+}
+
+// implements returns true if all the names are in methods. An empty list is never implemented.
+func implements(methods map[string]*GoType, names []string) bool {
+	for _, name := range names {
+		if _, ok := methods[name]; !ok {
+			return false
+		}
+	}
+	return len(names) > 0
+}
+
+// resolveMethodSets returns the sets with the methods of the embedded interfaces. The first
+// set of a given name wins, and the sets embedding an unknown interface are dropped as their
+// methods cannot be known.
+func resolveMethodSets(sets []MethodSet) []MethodSet {
+	byName := map[string]MethodSet{}
+	names := []string{}
+	for _, set := range sets {
+		if _, ok := byName[set.Name]; !ok {
+			byName[set.Name] = set
+			names = append(names, set.Name)
+		}
+	}
+
+	var resolve func(name string, visiting map[string]bool) ([]string, bool)
+	resolve = func(name string, visiting map[string]bool) ([]string, bool) {
+		set, ok := byName[name]
+		if !ok || visiting[name] {
+			return nil, false
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		methods := []string{}
+		for _, embed := range set.Embeds {
+			embedded, ok := resolve(embed, visiting)
+			if !ok {
+				return nil, false
+			}
+			methods = append(methods, embedded...)
+		}
+		return append(methods, set.Methods...), true
+	}
+
+	resolved := make([]MethodSet, 0, len(names))
+	for _, name := range names {
+		if methods, ok := resolve(name, map[string]bool{}); ok {
+			resolved = append(resolved, MethodSet{Name: name, Methods: methods})
+		}
+	}
+	return resolved
+}
@@ -2,13 +2,14 @@ package ordering
 
 import (
 	"crypto/sha256"
-	"errors"
 	"fmt"
 	"go/format"
+	"go/token"
 	"os"
 	"os/exec"
 	"sort"
 	"strings"
+	"text/template"
 )
 
 const reorderSignature = "// -- "
@@ -19,25 +20,34 @@ const reorderSignature = "// -- "
 // will be moved.
 var DefaultOrder = []Order{Const, Var, Interface, Type, Func}
 
+// typeGroup contains the declarations placed with a type, in addition to its constructors
+// and methods.
+type typeGroup struct {
+	afterType     []*GoType // right after the type declaration
+	values        []*GoType // associated consts and vars
+	beforeMethods []*GoType // after the constructors
+	afterMethods  []*GoType // after the methods
+}
+
 // findMissingOrderElement finds the missing order element.
-// If the default order is not complete, it will add the missing elements.
+// If the default order is not complete, it will add the missing elements. The finer kinds
+// (struct...) and the name patterns (type:*Error) do not replace a kind, whatever the length
+// of the list.
 func findMissingOrderElement(opt *ReorderConfig) {
 
-	if len(opt.DefOrder) != len(DefaultOrder) {
-		// wich one is missing?
-		for _, order := range DefaultOrder {
-			found := false
-			for _, defOrder := range opt.DefOrder {
-				if order == defOrder {
-					found = true
-					break
-				}
-			}
-			if !found {
-				// add it to the end
-				opt.DefOrder = append(opt.DefOrder, order)
+	// wich one is missing?
+	for _, order := range DefaultOrder {
+		found := false
+		for _, defOrder := range opt.DefOrder {
+			if order == defOrder {
+				found = true
+				break
 			}
 		}
+		if !found {
+			// add it to the end
+			opt.DefOrder = append(opt.DefOrder, order)
+		}
 	}
 }
 
@@ -52,12 +62,12 @@ func formatSource(content, output []byte, opt ReorderConfig) ([]byte, error) {
 		// format the temporary file
 		newcontent, err = format.Source([]byte(output))
 		if err != nil {
-			return content, errors.New("Failed to format source: " + err.Error())
+			return content, &FormatError{Command: opt.FormatCommand, Err: err}
 		}
 	default:
 		newcontent, err = formatWithCommand(content, output, opt)
 		if err != nil {
-			return content, errors.New("Failed to format source: " + err.Error())
+			return content, err
 		}
 	}
 
@@ -69,29 +79,54 @@ func formatWithCommand(content []byte, output []byte, opt ReorderConfig) (newcon
 	// on a temporary file we need to create and remove
 	tmpfile, err := os.CreateTemp("", "")
 	if err != nil {
-		return content, errors.New("Failed to create temp file: " + err.Error())
+		return content, &FormatError{Command: opt.FormatCommand, Err: fmt.Errorf("failed to create temp file: %w", err)}
 	}
 	defer os.Remove(tmpfile.Name())
 
 	// write the temporary file
 	if _, err := tmpfile.Write(output); err != nil {
-		return content, errors.New("Failed to write temp file: " + err.Error())
+		return content, &FormatError{Command: opt.FormatCommand, Err: fmt.Errorf("failed to write temp file: %w", err)}
 	}
 	tmpfile.Close()
 
 	// format the temporary file
+	var stderr strings.Builder
 	cmd := exec.Command(opt.FormatCommand, "-w", tmpfile.Name())
+	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return content, err
+		return content, &FormatError{Command: opt.FormatCommand, Stderr: stderr.String(), Err: err}
 	}
 	// read the temporary file
 	newcontent, err = os.ReadFile(tmpfile.Name())
 	if err != nil {
-		return content, errors.New("Read Temporary File error: " + err.Error())
+		return content, &FormatError{Command: opt.FormatCommand, Err: fmt.Errorf("failed to read temp file: %w", err)}
 	}
 	return newcontent, nil
 }
 
+// applyPriority moves the names of the priority list first, in the given order. The other
+// names keep their order.
+func applyPriority(names []string, priority []string) []string {
+	if len(priority) == 0 {
+		return names
+	}
+	result := make([]string, 0, len(names))
+	for _, name := range priority {
+		for _, n := range names {
+			if n == name {
+				result = append(result, n)
+				break
+			}
+		}
+	}
+	for _, name := range names {
+		if !inList(name, priority) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
 func getKeys(m map[string]*GoType) []string {
 	keys := make([]string, len(m))
 	i := 0
@@ -180,12 +215,12 @@ func processFunctions(
 
 func processInterfaces(
 	info *ParsedInfo,
-	originalContent, source []string,
+	interfaceNames, originalContent, source []string,
 	removedLines, lineNumberWhereInject *int,
 	sign string,
 ) []string {
 
-	for _, name := range *info.InterfaceNames {
+	for _, name := range interfaceNames {
 		sourceCode := info.Interfaces[name]
 		if *removedLines == 0 {
 			*lineNumberWhereInject = info.Interfaces[name].OpeningLine
@@ -201,12 +236,13 @@ func processInterfaces(
 
 func processTypes(
 	info *ParsedInfo,
-	originalContent, source []string,
+	typeNames, originalContent, source []string,
 	removedLines, lineNumberWhereInject *int,
 	sign string,
+	groups map[string]*typeGroup,
 ) []string {
 
-	for _, typename := range *info.TypeNames {
+	for _, typename := range typeNames {
 		if *removedLines == 0 {
 			*lineNumberWhereInject = info.Types[typename].OpeningLine
 		}
@@ -218,6 +254,22 @@ func processTypes(
 		// add the struct definition to "source"
 		source = append(source, info.Types[typename].SourceCode)
 
+		group := groups[typename]
+		if group == nil {
+			group = &typeGroup{}
+		}
+
+		for _, decl := range group.afterType {
+			source = moveDeclaration(decl, originalContent, source, sign, "\n")
+		}
+		*removedLines += len(group.afterType)
+
+		// associated consts and vars
+		for _, value := range group.values {
+			source = moveDeclaration(value, originalContent, source, sign, "\n")
+		}
+		*removedLines += len(group.values)
+
 		// same for constructors
 		for _, constructor := range info.Constructors[typename] {
 			for ln := constructor.OpeningLine - 1; ln < constructor.ClosingLine; ln++ {
@@ -228,6 +280,11 @@ func processTypes(
 		}
 		*removedLines += len(info.Constructors[typename])
 
+		for _, decl := range group.beforeMethods {
+			source = moveDeclaration(decl, originalContent, source, sign, "\n")
+		}
+		*removedLines += len(group.beforeMethods)
+
 		// same for methods
 		for _, method := range info.Methods[typename] {
 			for ln := method.OpeningLine - 1; ln < method.ClosingLine; ln++ {
@@ -237,10 +294,24 @@ func processTypes(
 			source = append(source, "\n"+method.SourceCode)
 		}
 		*removedLines += len(info.Methods[typename])
+
+		for _, decl := range group.afterMethods {
+			source = moveDeclaration(decl, originalContent, source, sign, "\n")
+		}
+		*removedLines += len(group.afterMethods)
 	}
 	return source
 }
 
+// moveDeclaration marks the lines of the declaration to be removed, and appends its source
+// code, with the given prefix, to source.
+func moveDeclaration(decl *GoType, originalContent, source []string, sign, prefix string) []string {
+	for ln := decl.OpeningLine - 1; ln < decl.ClosingLine; ln++ {
+		originalContent[ln] = reorderSignature + sign
+	}
+	return append(source, prefix+decl.SourceCode)
+}
+
 func processVars(
 	info *ParsedInfo,
 	varNames, originalContent, source []string,
@@ -261,6 +332,142 @@ func processVars(
 	return source
 }
 
+// attachHelpers removes from functionNames the unexported functions that are only used by
+// one type (its methods, constructors or other helpers), or that take this type as first
+// parameter, and adds them after the methods of the type.
+func attachHelpers(
+	info *ParsedInfo,
+	groups map[string]*typeGroup,
+	functionNames []string,
+	claims map[*GoType]int,
+) []string {
+	// owner of each helper
+	owners := map[string]string{}
+	isCandidate := func(name string) bool {
+		_, claimed := claims[info.Functions[name]]
+		return !claimed && !token.IsExported(name) && name != "init" && name != "main"
+	}
+	for _, name := range functionNames {
+		if t := info.Functions[name].FirstParamType; isCandidate(name) {
+			if _, ok := info.Types[t]; ok {
+				owners[name] = t
+			}
+		}
+	}
+
+	// users of each function: the type for methods, constructors and helpers, "" otherwise
+	for changed := true; changed; {
+		changed = false
+		users := map[string]map[string]bool{}
+		use := func(user, caller string, calls []string) {
+			for _, call := range calls {
+				if _, ok := info.Functions[call]; !ok || call == caller {
+					continue
+				}
+				if users[call] == nil {
+					users[call] = map[string]bool{}
+				}
+				users[call][user] = true
+			}
+		}
+		for typename, methods := range info.Methods {
+			for _, method := range methods {
+				use(typename, "", method.Calls)
+			}
+		}
+		for typename, constructors := range info.Constructors {
+			for _, constructor := range constructors {
+				use(typename, "", constructor.Calls)
+			}
+		}
+		for name, function := range info.Functions {
+			use(owners[name], name, function.Calls)
+		}
+
+		for _, name := range functionNames {
+			if _, ok := owners[name]; ok || !isCandidate(name) || len(users[name]) != 1 {
+				continue
+			}
+			for user := range users[name] {
+				if _, ok := info.Types[user]; ok && user != "" {
+					owners[name] = user
+					changed = true
+				}
+			}
+		}
+	}
+
+	remaining := []string{}
+	for _, name := range functionNames {
+		owner, ok := owners[name]
+		if !ok {
+			remaining = append(remaining, name)
+			continue
+		}
+		if groups[owner] == nil {
+			groups[owner] = &typeGroup{}
+		}
+		groups[owner].afterMethods = append(groups[owner].afterMethods, info.Functions[name])
+	}
+	return remaining
+}
+
+// attachAssertions removes from varNames the interface assertions on a type of the file,
+// and adds them to the type group, at the given placement.
+func attachAssertions(
+	info *ParsedInfo,
+	groups map[string]*typeGroup,
+	varNames []string,
+	placement string,
+) []string {
+	remaining := []string{}
+	for _, name := range varNames {
+		decl := info.Variables[name]
+		if _, ok := info.Types[decl.AssertedType]; !ok {
+			remaining = append(remaining, name)
+			continue
+		}
+		if groups[decl.AssertedType] == nil {
+			groups[decl.AssertedType] = &typeGroup{}
+		}
+		group := groups[decl.AssertedType]
+		switch placement {
+		case AssertionsAfterType:
+			group.afterType = append(group.afterType, decl)
+		case AssertionsBeforeMethods:
+			group.beforeMethods = append(group.beforeMethods, decl)
+		}
+	}
+	return remaining
+}
+
+// associateValues removes from constNames and varNames the declarations associated with a
+// type of the file, and adds them to the type group.
+func associateValues(
+	info *ParsedInfo,
+	groups map[string]*typeGroup,
+	constNames, varNames []string,
+) ([]string, []string) {
+	associate := func(names []string, decls map[string]*GoType) []string {
+		remaining := []string{}
+		for _, name := range names {
+			typename := decls[name].AssociatedType
+			if _, ok := info.Types[typename]; !ok {
+				remaining = append(remaining, name)
+				continue
+			}
+			if groups[typename] == nil {
+				groups[typename] = &typeGroup{}
+			}
+			groups[typename].values = append(groups[typename].values, decls[name])
+		}
+		return remaining
+	}
+	constNames = associate(constNames, info.Constants)
+	varNames = associate(varNames, info.Variables)
+	return constNames, varNames
+}
+
 func removeSignedLine(originalContent []string, sign string) []string {
 	// remove the lines that were marked as "// -- line to remove"
 	temp := []string{}
@@ -273,12 +480,163 @@ func removeSignedLine(originalContent []string, sign string) []string {
 	return temp
 }
 
+// selectTypes returns the type names of the given kind. The generic "type" kind selects the
+// types whose finer kind is not in the order list.
+func selectTypes(info *ParsedInfo, kind Order, defOrder []Order) []string {
+	names := []string{}
+	for _, name := range *info.TypeNames {
+		typeKind := info.Types[name].Kind
+		switch {
+		case kind == typeKind:
+		case kind == Type && !inList(typeKind, defOrder):
+		default:
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func inList(s string, list []string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func sortGoTypes(v []*GoType) {
 	sort.Slice(v, func(i, j int) bool {
 		return v[i].Name < v[j].Name
 	})
 }
 
+// sortMethods sorts the methods with the given keys (MethodOrderKeys), then by name. If
+// pairAccessors is true, the getters and setters are sorted by property name, and each
+// setter is placed right after its getter.
+func sortMethods(v []*GoType, keys []string, pairAccessors bool) {
+	wellKnown := func(m *GoType) int {
+		for i, name := range WellKnownMethods {
+			if m.Name == name {
+				return i
+			}
+		}
+		return -1
+	}
+	getters := map[*GoType]*GoType{} // setter -> getter
+	sortNames := map[*GoType]string{}
+	if pairAccessors {
+		getters = accessorPairs(v)
+		for setter, getter := range getters {
+			sortNames[setter] = accessorProperty(setter.Name)
+			sortNames[getter] = sortNames[setter]
+		}
+	}
+	sortName := func(m *GoType) string {
+		if name, ok := sortNames[m]; ok {
+			return name
+		}
+		return m.Name
+	}
+	sort.SliceStable(v, func(i, j int) bool {
+		for _, key := range keys {
+			switch key {
+			case MethodExported:
+				if a, b := token.IsExported(v[i].Name), token.IsExported(v[j].Name); a != b {
+					return a
+				}
+			case MethodReceiver:
+				if v[i].PointerReceiver != v[j].PointerReceiver {
+					return !v[i].PointerReceiver
+				}
+			case MethodWellKnown:
+				if a, b := wellKnown(v[i]), wellKnown(v[j]); a != b {
+					return a < b
+				}
+			case MethodName:
+				if a, b := sortName(v[i]), sortName(v[j]); a != b {
+					return a < b
+				}
+			}
+		}
+		if a, b := sortName(v[i]), sortName(v[j]); a != b {
+			return a < b
+		}
+		return v[i].Name < v[j].Name
+	})
+
+	// the other keys can separate a pair, setters are moved after their getter
+	if len(getters) == 0 {
+		return
+	}
+	setters := make(map[*GoType]*GoType, len(getters))
+	for setter, getter := range getters {
+		setters[getter] = setter
+	}
+	sorted := make([]*GoType, 0, len(v))
+	for _, m := range v {
+		if _, ok := getters[m]; ok {
+			continue
+		}
+		sorted = append(sorted, m)
+		if setter, ok := setters[m]; ok {
+			sorted = append(sorted, setter)
+		}
+	}
+	copy(v, sorted)
+}
+
+// accessorPairs returns the getter of each setter. A setter is named SetX (or setX), its
+// getter is X, GetX, IsX or HasX (or the unexported forms), in this order of preference.
+// A method belongs to one pair at most.
+func accessorPairs(methods []*GoType) map[*GoType]*GoType {
+	byName := make(map[string]*GoType, len(methods))
+	for _, m := range methods {
+		byName[m.Name] = m
+	}
+	paired := map[*GoType]bool{}
+	pairs := map[*GoType]*GoType{}
+	for _, setter := range methods {
+		property := accessorProperty(setter.Name)
+		if property == "" || paired[setter] {
+			continue
+		}
+		prefixes := []string{"", "Get", "Is", "Has"}
+		if !token.IsExported(setter.Name) {
+			prefixes = []string{"", "get", "is", "has"}
+		}
+		for _, prefix := range prefixes {
+			name := property
+			if prefix != "" {
+				name = prefix + strings.ToUpper(property[:1]) + property[1:]
+			}
+			if getter, ok := byName[name]; ok && !paired[getter] && accessorProperty(name) == "" {
+				pairs[setter] = getter
+				paired[setter], paired[getter] = true, true
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+// accessorProperty returns the property name of a setter, "Name" for SetName and "name" for
+// setName, or an empty string if name is not a setter.
+func accessorProperty(name string) string {
+	for _, prefix := range []string{"Set", "set"} {
+		property, ok := strings.CutPrefix(name, prefix)
+		if !ok || property == "" || !token.IsExported(property) {
+			continue
+		}
+		if prefix == "set" {
+			property = strings.ToLower(property[:1]) + property[1:]
+		}
+		return property
+	}
+	return ""
+}
+
 // ReorderSource reorders the source code in the given filename.
 // It will be helped by the formatCommand (gofmt or goimports).
 // If gofmt is used, the source code will be formatted with the go/fmt package in memory.
@@ -294,9 +652,20 @@ func ReorderSource(opt ReorderConfig) (string, error) {
 		opt.DefOrder = DefaultOrder
 	}
 	findMissingOrderElement(&opt)
+	rules, err := parseOrderRules(opt.DefOrder)
+	if err != nil {
+		return "", err
+	}
+	for _, key := range opt.MethodOrder {
+		if !inList(key, MethodOrderKeys) {
+			return "", fmt.Errorf("invalid method order %q, allowed values are %s", key, strings.Join(MethodOrderKeys, ", "))
+		}
+	}
+	if opt.Spacing != "" && !inList(opt.Spacing, SpacingPolicies) {
+		return "", fmt.Errorf("invalid spacing %q, allowed values are %s", opt.Spacing, strings.Join(SpacingPolicies, ", "))
+	}
 
 	var content []byte
-	var err error
 	if opt.Src == nil || len(opt.Src.([]byte)) == 0 {
 		content, err = os.ReadFile(opt.Filename)
 		if err != nil {
@@ -306,19 +675,49 @@ func ReorderSource(opt ReorderConfig) (string, error) {
 		content = opt.Src.([]byte)
 	}
 
+	// the content can be changed before parsing (banners, fields), the diff is made with
+	// the original content
+	original := content
+	var banners *template.Template
+	if opt.Banners {
+		if opt.BannerTemplate == "" {
+			opt.BannerTemplate = DefaultBannerTemplate
+		}
+		if banners, err = ParseBannerTemplate(opt.BannerTemplate); err != nil {
+			return "", err
+		}
+		if content, err = stripBanners(content, banners, rules); err != nil {
+			return "", err
+		}
+	}
+	if opt.SortStructFields || opt.SortInterfaceMethods {
+		sorted, err := sortDeclarationFields(opt.Filename, content, opt.SortStructFields, opt.SortInterfaceMethods)
+		if err != nil {
+			return string(original), err
+		}
+		content = sorted
+	}
+	if opt.AlignStructs {
+		aligned, err := alignStructs(opt.Filename, content, opt.AlignArch)
+		if err != nil {
+			return string(original), err
+		}
+		content = aligned
+	}
+
 	info, err := Parse(opt.Filename, content)
 
 	if err != nil {
-		return string(content), errors.New("Error parsing source: " + err.Error())
+		return string(content), newParseError(opt.Filename, err)
 	}
 
 	//if len(info.Types) == 0 {
 	//	return string(content), errors.New("No structs found in " + opt.Filename + ", cannot reorder")
 	//}
 
-	// sort methods by name
+	// sort methods by name, or with the method order keys
 	for _, method := range info.Methods {
-		sortGoTypes(method)
+		sortMethods(method, opt.MethodOrder, opt.PairAccessors)
 	}
 
 	for _, constructor := range info.Constructors {
@@ -338,6 +737,38 @@ func ReorderSource(opt ReorderConfig) (string, error) {
 
 	info.InterfaceNames.Sort()
 
+	if opt.Stepdown {
+		functionNames = stepdownOrder(functionNames, func(name string) []string {
+			return info.Functions[name].Calls
+		})
+		stepdownMethods(info.Methods)
+	}
+
+	if opt.GroupByInterface {
+		sets := append(info.MethodSets(), opt.Interfaces...)
+		groupByInterface(info.Methods, append(sets, KnownInterfaces...))
+	}
+
+	// pinned names are placed first
+	*info.TypeNames = applyPriority(*info.TypeNames, opt.Priority[Type])
+	*info.InterfaceNames = applyPriority(*info.InterfaceNames, opt.Priority[Interface])
+	functionNames = applyPriority(functionNames, opt.Priority[Func])
+
+	// declarations matching a name pattern are taken before the generic kinds
+	claims := claimDeclarations(info, rules)
+
+	// declarations placed with a type, in addition to constructors and methods
+	groups := map[string]*typeGroup{}
+	if opt.Assertions == AssertionsAfterType || opt.Assertions == AssertionsBeforeMethods {
+		varNames = attachAssertions(info, groups, varNames, opt.Assertions)
+	}
+	if opt.Associate {
+		constNames, varNames = associateValues(info, groups, constNames, varNames)
+	}
+	if opt.ColocateHelpers {
+		functionNames = attachHelpers(info, groups, functionNames, claims)
+	}
+
 	// Get the source code signature - we will use this to mark the lines to remove later
 	sign := fmt.Sprintf("%x", sha256.Sum256(content))
 
@@ -361,38 +792,49 @@ func ReorderSource(opt ReorderConfig) (string, error) {
 		}
 	}
 
-	for _, order := range opt.DefOrder {
-		switch order {
+	for i, rule := range rules {
+		start := len(source)
+		// generic kinds take what is not claimed by a pattern
+		claim := i
+		if rule.match == nil {
+			claim = -1
+		}
+		switch rule.kind {
 		case Const:
 			source = processConst(
 				info,
-				constNames, originalContent, source,
+				claimedNames(constNames, info.Constants, claims, claim), originalContent, source,
 				&removedLines, &lineNumberWhereInject,
 				sign)
 		case Var:
 			source = processVars(
 				info,
-				varNames, originalContent, source,
+				claimedNames(varNames, info.Variables, claims, claim), originalContent, source,
 				&removedLines, &lineNumberWhereInject,
 				sign)
 		case Interface:
 			source = processInterfaces(
 				info,
-				originalContent, source,
+				claimedNames(*info.InterfaceNames, info.Interfaces, claims, claim), originalContent, source,
 				&removedLines, &lineNumberWhereInject,
 				sign,
 			)
-		case Type:
+		case Type, Struct, Alias, FuncType, Basic:
+			typeNames := *info.TypeNames
+			if rule.match == nil {
+				typeNames = selectTypes(info, rule.kind, opt.DefOrder)
+			}
 			source = processTypes(
 				info,
-				originalContent, source,
+				claimedNames(typeNames, info.Types, claims, claim), originalContent, source,
 				&removedLines, &lineNumberWhereInject,
 				sign,
+				groups,
 			)
 		case Func:
 			source = processFunctions(
 				info,
-				functionNames, originalContent, source,
+				claimedNames(functionNames, info.Functions, claims, claim), originalContent, source,
 				&removedLines, &lineNumberWhereInject,
 				sign,
 				extactinit, extractmain,
@@ -414,6 +856,23 @@ func ReorderSource(opt ReorderConfig) (string, error) {
 				"main",
 			)
 		}
+
+		// the banner is separated from the declarations, so it is not taken as a doc comment
+		if banners != nil && len(source) > start {
+			banner, err := renderBanner(banners, rule)
+			if err != nil {
+				return "", err
+			}
+			source = append(source[:start], append([]string{"\n" + strings.Join(banner, "\n") + "\n"}, source[start:]...)...)
+		}
+	}
+
+	// gofmt keeps at most one blank line, and always one between declarations of different
+	// kinds (e.g. a type and its constructor), it only has to be added between the others
+	if opt.Spacing == SpacingOne {
+		for i, entry := range source {
+			source[i] = "\n" + strings.Trim(entry, "\n")
+		}
 	}
 
 	// add the "source" at the found lineNumberWhereInject
@@ -425,13 +884,16 @@ func ReorderSource(opt ReorderConfig) (string, error) {
 
 	// write in a temporary file and use "gofmt" to format it
 	//newcontent := []byte(output)
-	newcontent, err := formatSource(content, output, opt)
+	newcontent, err := formatSource(original, output, opt)
 	if err != nil {
-		return string(content), err
+		return string(original), err
+	}
+	if err := verifyOutput(opt.Filename, content, newcontent); err != nil {
+		return string(original), err
 	}
 
 	if opt.Diff {
-		return doDiff(content, newcontent, opt.Filename)
+		return doDiff(original, newcontent, opt.Filename)
 	}
 	return string(newcontent), nil
 }
@@ -1,10 +1,15 @@
 package ordering
 
 import (
+	"errors"
+	"go/scanner"
+	"go/types"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -726,3 +731,1207 @@ type Foo struct {
 	}
 
 }
+
+func TestTypeKinds(t *testing.T) {
+	const source = `package main
+
+type Handler func(string) error
+
+type Celsius float64
+
+type Reader = io.Reader
+
+type List []string
+
+type Foo struct{}
+
+func (f Foo) Bar() {}
+
+type Bar interface {
+	Bar()
+}
+`
+	const expected = `package main
+
+type Bar interface {
+	Bar()
+}
+type Foo struct{}
+
+func (f Foo) Bar() {}
+
+type Reader = io.Reader
+type Handler func(string) error
+type Celsius float64
+type List []string
+`
+	info, err := Parse("foo.go", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, kind := range map[string]Order{
+		"Handler": FuncType,
+		"Celsius": Basic,
+		"Reader":  Alias,
+		"List":    Type,
+		"Foo":     Struct,
+	} {
+		if info.Types[name].Kind != kind {
+			t.Errorf("%s should be a %s, got %s", name, kind, info.Types[name].Kind)
+		}
+	}
+
+	content, err := ReorderSource(ReorderConfig{
+		Filename:       "foo.go",
+		FormatCommand:  "gofmt",
+		ReorderStructs: true,
+		Src:            []byte(source),
+		DefOrder:       []Order{Const, Var, Interface, Struct, Alias, FuncType, Type, Func},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+}
+
+func TestMissingOrderElement(t *testing.T) {
+	// the kinds and name patterns do not replace a missing kind of the default order
+	const source = `package main
+
+type I interface{}
+
+func f() {}
+
+type B struct{}
+
+const X = 1
+
+type A struct{}
+`
+	const expected = `package main
+
+const X = 1
+
+type A struct{}
+type B struct{}
+
+func f() {}
+
+type I interface{}
+`
+	for _, order := range [][]Order{
+		{Const, Var, Struct, Type, Func},
+		{Const, Var, "type:A*", Type, Func},
+	} {
+		content, err := ReorderSource(ReorderConfig{
+			Filename:       "foo.go",
+			FormatCommand:  "gofmt",
+			Src:            []byte(source),
+			DefOrder:       order,
+			ReorderStructs: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content != expected {
+			t.Errorf("With the order %v, expected:\n%s\nGot:\n%s\n", order, expected, content)
+		}
+	}
+}
+
+func TestNamePatterns(t *testing.T) {
+	const source = `package main
+
+var b = 1
+
+var ErrNotFound = errors.New("not found")
+
+type NotFoundError struct{}
+
+func (e NotFoundError) Error() string { return "" }
+
+type Foo struct{}
+
+func bar() {}
+
+func MustFoo() Foo { return Foo{} }
+
+func MustBar() {}
+`
+	const expected = `package main
+
+var ErrNotFound = errors.New("not found")
+var b = 1
+
+type Foo struct{}
+
+func MustFoo() Foo { return Foo{} }
+
+func MustBar() {}
+
+func bar() {}
+
+type NotFoundError struct{}
+
+func (e NotFoundError) Error() string { return "" }
+`
+	content, err := ReorderSource(ReorderConfig{
+		Filename:       "foo.go",
+		FormatCommand:  "gofmt",
+		ReorderStructs: true,
+		Src:            []byte(source),
+		DefOrder:       []Order{"var:Err*", Var, Type, "func:/^Must/", Func, "type:*Error"},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+
+	if _, err := ReorderSource(ReorderConfig{
+		Filename:      "foo.go",
+		FormatCommand: "gofmt",
+		Src:           []byte(source),
+		DefOrder:      []Order{"func:/(/"},
+	}); err == nil {
+		t.Error("an error should occur with an invalid pattern")
+	}
+}
+
+func TestPriority(t *testing.T) {
+	const source = `package main
+
+type Server struct{}
+
+func (s *Server) Start() {}
+
+type Options struct{}
+type Config struct{}
+type Alpha struct{}
+
+func helper() {}
+func NewServer() {}
+`
+	const expected = `package main
+
+type Config struct{}
+type Options struct{}
+type Server struct{}
+
+func (s *Server) Start() {}
+
+type Alpha struct{}
+
+func NewServer() {}
+
+func helper() {}
+`
+	content, err := ReorderSource(ReorderConfig{
+		Filename:       "foo.go",
+		FormatCommand:  "gofmt",
+		ReorderStructs: true,
+		Src:            []byte(source),
+		Priority: map[Order][]string{
+			Type: {"Config", "Options", "Server", "Unknown"},
+			Func: {"NewServer"},
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+}
+
+func TestAssociate(t *testing.T) {
+	const source = `package main
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+
+const other = 1
+
+var DefaultColor Color = Red
+
+type Color int
+
+func (c Color) String() string { return "" }
+
+func NewColor() Color { return Red }
+
+var (
+	a = 1
+	b int
+)
+`
+	const expected = `package main
+
+const other = 1
+
+var (
+	a = 1
+	b int
+)
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+
+var DefaultColor Color = Red
+
+func NewColor() Color { return Red }
+
+func (c Color) String() string { return "" }
+`
+	content, err := ReorderSource(ReorderConfig{
+		Filename:      "foo.go",
+		FormatCommand: "gofmt",
+		Src:           []byte(source),
+		Associate:     true,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	const source = `package main
+
+var _ sort.Interface = (*List)(nil)
+
+var (
+	_ fmt.Stringer = List{}
+	_ io.Reader    = &List{}
+)
+
+var _ io.Writer = (*Other)(nil)
+var a = 1
+
+type List []string
+
+func (l List) Len() int { return len(l) }
+
+func NewList() List { return nil }
+`
+	const afterType = `package main
+
+var _ io.Writer = (*Other)(nil)
+var a = 1
+
+type List []string
+
+var _ sort.Interface = (*List)(nil)
+
+var (
+	_ fmt.Stringer = List{}
+	_ io.Reader    = &List{}
+)
+
+func NewList() List { return nil }
+
+func (l List) Len() int { return len(l) }
+`
+	const beforeMethods = `package main
+
+var _ io.Writer = (*Other)(nil)
+var a = 1
+
+type List []string
+
+func NewList() List { return nil }
+
+var _ sort.Interface = (*List)(nil)
+
+var (
+	_ fmt.Stringer = List{}
+	_ io.Reader    = &List{}
+)
+
+func (l List) Len() int { return len(l) }
+`
+	for placement, expected := range map[string]string{
+		AssertionsAfterType:     afterType,
+		AssertionsBeforeMethods: beforeMethods,
+	} {
+		content, err := ReorderSource(ReorderConfig{
+			Filename:      "foo.go",
+			FormatCommand: "gofmt",
+			Src:           []byte(source),
+			Assertions:    placement,
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		if content != expected {
+			t.Errorf("Expected (%s):\n%s\nGot:\n%s\n", placement, expected, content)
+		}
+	}
+}
+
+func TestStepdown(t *testing.T) {
+	const source = `package main
+
+func c() {}
+
+func b() {
+	c()
+}
+
+func a() {
+	b()
+	d()
+}
+
+func d() {}
+
+func Run() {
+	a()
+	sort.Slice(nil, less)
+}
+
+func less(i, j int) {}
+
+func cycle1() { cycle2() }
+
+func cycle2() { cycle1() }
+
+type Foo struct{}
+
+func (f *Foo) helper() {}
+
+func (f *Foo) Bar() {
+	f.helper()
+}
+
+func (f *Foo) Alpha() {}
+`
+	const expected = `package main
+
+type Foo struct{}
+
+func (f *Foo) Alpha() {}
+
+func (f *Foo) Bar() {
+	f.helper()
+}
+
+func (f *Foo) helper() {}
+
+func Run() {
+	a()
+	sort.Slice(nil, less)
+}
+
+func a() {
+	b()
+	d()
+}
+
+func b() {
+	c()
+}
+
+func c() {}
+
+func d() {}
+
+func less(i, j int) {}
+
+func cycle1() { cycle2() }
+
+func cycle2() { cycle1() }
+`
+	content, err := ReorderSource(ReorderConfig{
+		Filename:      "foo.go",
+		FormatCommand: "gofmt",
+		Src:           []byte(source),
+		Stepdown:      true,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+}
+
+func TestColocateHelpers(t *testing.T) {
+	const source = `package main
+
+func shared() {}
+
+func fooHelper() { deep() }
+
+func deep() {}
+
+func recursive(n int) {
+	if n > 0 {
+		recursive(n - 1)
+	}
+}
+
+func resetBar(b *Bar) {}
+
+func Exported(b *Bar) {}
+
+type Foo struct{}
+
+func (f *Foo) Run() {
+	fooHelper()
+	shared()
+	recursive(2)
+}
+
+type Bar struct{}
+
+func NewBar() *Bar {
+	shared()
+	return &Bar{}
+}
+
+func (b *Bar) Reset() {}
+`
+	const expected = `package main
+
+type Bar struct{}
+
+func NewBar() *Bar {
+	shared()
+	return &Bar{}
+}
+
+func (b *Bar) Reset() {}
+
+func resetBar(b *Bar) {}
+
+type Foo struct{}
+
+func (f *Foo) Run() {
+	fooHelper()
+	shared()
+	recursive(2)
+}
+
+func deep() {}
+
+func fooHelper() { deep() }
+
+func recursive(n int) {
+	if n > 0 {
+		recursive(n - 1)
+	}
+}
+
+func Exported(b *Bar) {}
+
+func shared() {}
+`
+	content, err := ReorderSource(ReorderConfig{
+		Filename:        "foo.go",
+		FormatCommand:   "gofmt",
+		Src:             []byte(source),
+		ReorderStructs:  true,
+		ColocateHelpers: true,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+}
+
+func TestGroupByInterface(t *testing.T) {
+	const source = `package main
+
+import "io"
+
+type Shape interface {
+	Perimeter() float64
+	Area() float64
+}
+
+type Solid interface {
+	Shape
+	Volume() float64
+}
+
+type Cube struct{}
+
+func (c Cube) Area() float64      { return 0 }
+func (c Cube) Name()              {}
+func (c Cube) Perimeter() float64 { return 0 }
+func (c Cube) String() string     { return "" }
+func (c Cube) Volume() float64    { return 0 }
+
+type List []int
+
+func (l List) Add(i int)          {}
+func (l List) Close() error       { return nil }
+func (l List) Len() int           { return len(l) }
+func (l List) Less(i, j int) bool { return l[i] < l[j] }
+func (l List) Save()              {}
+func (l List) Swap(i, j int)      {}
+func (l List) Write(p []byte) (int, error) { return 0, nil }
+
+var _ io.WriteCloser = List{}
+`
+	const expected = `package main
+
+import "io"
+
+var _ io.WriteCloser = List{}
+
+type Shape interface {
+	Perimeter() float64
+	Area() float64
+}
+type Solid interface {
+	Shape
+	Volume() float64
+}
+type Cube struct{}
+
+func (c Cube) Perimeter() float64 { return 0 }
+
+func (c Cube) Area() float64 { return 0 }
+
+func (c Cube) Volume() float64 { return 0 }
+
+func (c Cube) String() string { return "" }
+
+func (c Cube) Name() {}
+
+type List []int
+
+func (l List) Save() {}
+
+func (l List) Len() int { return len(l) }
+
+func (l List) Less(i, j int) bool { return l[i] < l[j] }
+
+func (l List) Swap(i, j int) {}
+
+func (l List) Write(p []byte) (int, error) { return 0, nil }
+
+func (l List) Close() error { return nil }
+
+func (l List) Add(i int) {}
+`
+	content, err := ReorderSource(ReorderConfig{
+		Filename:         "foo.go",
+		FormatCommand:    "gofmt",
+		Src:              []byte(source),
+		ReorderStructs:   true,
+		GroupByInterface: true,
+		// declared in another file of the package
+		Interfaces: []MethodSet{{Name: "Saver", Methods: []string{"Save"}}},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+}
+
+func TestMethodOrder(t *testing.T) {
+	const source = `package main
+
+type Foo struct{}
+
+func (f *Foo) Close() error  { return nil }
+func (f Foo) String() string { return "" }
+func (f *Foo) reset()        {}
+func (f Foo) Value() int     { return 0 }
+func (f *Foo) Apply()        {}
+func (f Foo) name() string   { return "" }
+`
+	tests := map[string]struct {
+		keys     []string
+		expected []string
+	}{
+		"default":   {nil, []string{"Apply", "Close", "String", "Value", "name", "reset"}},
+		"exported":  {[]string{MethodExported}, []string{"Apply", "Close", "String", "Value", "name", "reset"}},
+		"receiver":  {[]string{MethodReceiver, MethodName}, []string{"String", "Value", "name", "Apply", "Close", "reset"}},
+		"wellknown": {[]string{MethodWellKnown}, []string{"Apply", "Value", "name", "reset", "String", "Close"}},
+		"combined": {
+			[]string{MethodExported, MethodReceiver, MethodWellKnown, MethodName},
+			[]string{"Value", "String", "Apply", "Close", "name", "reset"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			content, err := ReorderSource(ReorderConfig{
+				Filename:      "foo.go",
+				FormatCommand: "gofmt",
+				Src:           []byte(source),
+				MethodOrder:   test.keys,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := -1
+			for _, method := range test.expected {
+				pos := strings.Index(content, ") "+method+"(")
+				if pos < last {
+					t.Errorf("%s is not at the expected place, expected order %v, got:\n%s", method, test.expected, content)
+				}
+				last = pos
+			}
+		})
+	}
+
+	_, err := ReorderSource(ReorderConfig{
+		Filename:      "foo.go",
+		FormatCommand: "gofmt",
+		Src:           []byte(source),
+		MethodOrder:   []string{"visibility"},
+	})
+	if err == nil {
+		t.Error("an error should occur with an invalid method order")
+	}
+}
+
+func TestPairAccessors(t *testing.T) {
+	const source = `package main
+
+type Foo struct{}
+
+func (f *Foo) SetName(name string) {}
+func (f *Foo) Apply()              {}
+func (f *Foo) SetEnabled(b bool)   {}
+func (f Foo) Name() string         { return "" }
+func (f Foo) IsEnabled() bool      { return true }
+func (f *Foo) Reset()              {}
+func (f *Foo) SetSize(size int)    {}
+func (f *Foo) setLevel(l int)      {}
+func (f *Foo) getLevel() int       { return 0 }
+`
+	tests := map[string]struct {
+		keys     []string
+		expected []string
+	}{
+		"name": {
+			nil,
+			[]string{"Apply", "IsEnabled", "SetEnabled", "Name", "SetName", "Reset", "SetSize", "getLevel", "setLevel"},
+		},
+		"receiver": {
+			[]string{MethodReceiver, MethodName},
+			[]string{"IsEnabled", "SetEnabled", "Name", "SetName", "Apply", "Reset", "SetSize", "getLevel", "setLevel"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			content, err := ReorderSource(ReorderConfig{
+				Filename:      "foo.go",
+				FormatCommand: "gofmt",
+				Src:           []byte(source),
+				MethodOrder:   test.keys,
+				PairAccessors: true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := -1
+			for _, method := range test.expected {
+				pos := strings.Index(content, ") "+method+"(")
+				if pos < last {
+					t.Errorf("%s is not at the expected place, expected order %v, got:\n%s", method, test.expected, content)
+				}
+				last = pos
+			}
+		})
+	}
+}
+
+func TestSortFields(t *testing.T) {
+	const source = `package main
+
+import (
+	"io"
+	"sync"
+)
+
+type Config struct {
+	// Name of the config
+	Name string ` + "`yaml:\"name\"`" + `
+	Debug bool // verbose output
+	sync.Mutex
+	Address struct {
+		Port int
+		Host string
+	}
+
+	Timeout int
+	Retries, Delay int
+}
+
+type Point struct {
+	Y int
+	X int
+}
+
+var origin = []Point{{0, 0}}
+
+type Small struct{ B, A int }
+
+type Store interface {
+	Save() error
+	Load() error
+	io.Closer
+}
+`
+	const expected = `package main
+
+import (
+	"io"
+	"sync"
+)
+
+var origin = []Point{{0, 0}}
+
+type Store interface {
+	io.Closer
+	Load() error
+	Save() error
+}
+type Config struct {
+	sync.Mutex
+	Address struct {
+		Port int
+		Host string
+	}
+	Debug bool // verbose output
+	// Name of the config
+	Name string ` + "`yaml:\"name\"`" + `
+
+	Retries, Delay int
+	Timeout        int
+}
+type Point struct {
+	Y int
+	X int
+}
+type Small struct{ B, A int }
+`
+	content, err := ReorderSource(ReorderConfig{
+		Filename:             "foo.go",
+		FormatCommand:        "gofmt",
+		Src:                  []byte(source),
+		SortStructFields:     true,
+		SortInterfaceMethods: true,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+}
+
+func TestAlignStructs(t *testing.T) {
+	const source = `package main
+
+import "sync"
+
+type Padded struct {
+	// A is first
+	A bool
+	B int64
+
+	C bool // trailing
+	mu sync.Mutex
+}
+
+type Mixed struct {
+	X int32
+	Y int64
+	Z int32
+}
+
+//goreorder:noalign
+type Kept struct {
+	A bool
+	B int64
+	C bool
+}
+
+type Header struct {
+	Flag bool  ` + "`binary:\"flag\"`" + `
+	Size int64
+	Kind bool
+}
+
+type Compact struct {
+	B int64
+	A bool
+}
+`
+	const expected = `package main
+
+import "sync"
+
+type Padded struct {
+	B  int64
+	mu sync.Mutex
+	// A is first
+	A bool
+	C bool // trailing
+}
+type Mixed struct {
+	Y int64
+	X int32
+	Z int32
+}
+
+//goreorder:noalign
+type Kept struct {
+	A bool
+	B int64
+	C bool
+}
+type Header struct {
+	Flag bool ` + "`binary:\"flag\"`" + `
+	Size int64
+	Kind bool
+}
+type Compact struct {
+	B int64
+	A bool
+}
+`
+	content, err := ReorderSource(ReorderConfig{
+		Filename:      "foo.go",
+		FormatCommand: "gofmt",
+		Src:           []byte(source),
+		AlignStructs:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+
+	// int64 is aligned on 4 bytes on 386, Mixed has no padding
+	content, err = ReorderSource(ReorderConfig{
+		Filename:      "foo.go",
+		FormatCommand: "gofmt",
+		Src:           []byte(source),
+		AlignStructs:  true,
+		AlignArch:     "386",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "type Mixed struct {\n\tX int32\n\tY int64\n\tZ int32\n}") {
+		t.Errorf("Mixed should not be changed on 386, got:\n%s", content)
+	}
+
+	_, err = ReorderSource(ReorderConfig{
+		Filename:      "foo.go",
+		FormatCommand: "gofmt",
+		Src:           []byte(source),
+		AlignStructs:  true,
+		AlignArch:     "z80",
+	})
+	if err == nil {
+		t.Error("an error should occur with an unknown architecture")
+	}
+}
+
+func TestAlignArchs(t *testing.T) {
+	for _, arch := range AlignArchs {
+		if types.SizesFor("gc", arch) == nil {
+			t.Errorf("%s is not known by go/types", arch)
+		}
+	}
+}
+
+func TestBanners(t *testing.T) {
+	const source = `package main
+
+func b() {}
+
+// Foo is a type
+type Foo struct{}
+
+func (f Foo) M() {}
+
+type NotFound struct{}
+
+func (e NotFound) Error() string { return "" }
+
+const X = 1
+`
+	const expected = `package main
+
+// ----------
+// Constants
+// ----------
+
+const X = 1
+
+// ----------
+// Types Not*
+// ----------
+
+type NotFound struct{}
+
+func (e NotFound) Error() string { return "" }
+
+// ----------
+// Types
+// ----------
+
+// Foo is a type
+type Foo struct{}
+
+func (f Foo) M() {}
+
+// ----------
+// Functions
+// ----------
+
+func b() {}
+`
+	config := ReorderConfig{
+		Filename:       "foo.go",
+		FormatCommand:  "gofmt",
+		Src:            []byte(source),
+		DefOrder:       []Order{Const, Var, Interface, "type:Not*", Type, Func},
+		ReorderStructs: true,
+		Banners:        true,
+		BannerTemplate: "// ----------\n// {{.Title}}\n// ----------",
+	}
+	content, err := ReorderSource(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+
+	// the banners are replaced on re-runs
+	config.Src = []byte(content)
+	content, err = ReorderSource(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != expected {
+		t.Errorf("Expected the same output on re-run:\n%s\nGot:\n%s\n", expected, content)
+	}
+
+	config.BannerTemplate = "{{.Title}}"
+	if _, err := ReorderSource(config); err == nil {
+		t.Error("an error should occur with a banner that is not a comment")
+	}
+}
+
+func TestBannersKeepComments(t *testing.T) {
+	// the strings and doc comments that match a banner are not banners
+	const source = `package main
+
+// Functions are the registered functions.
+var Functions = map[string]func(){}
+
+// Types
+var Types = []string{}
+
+const tmpl = ` + "`" + `
+// Types
+x
+` + "`" + `
+`
+	const expected = `package main
+
+// Constants
+
+const tmpl = ` + "`" + `
+// Types
+x
+` + "`" + `
+
+// Variables
+
+// Functions are the registered functions.
+var Functions = map[string]func(){}
+
+// Types
+var Types = []string{}
+`
+	config := ReorderConfig{
+		Filename:      "foo.go",
+		FormatCommand: "gofmt",
+		Src:           []byte(source),
+		Banners:       true,
+	}
+	content, err := ReorderSource(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+
+	config.Src = []byte(content)
+	content, err = ReorderSource(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != expected {
+		t.Errorf("Expected the same output on re-run:\n%s\nGot:\n%s\n", expected, content)
+	}
+}
+
+func TestSpacing(t *testing.T) {
+	const source = `package main
+
+func b() {}
+func a() {}
+const (
+	X = 1
+)
+const Y = 2
+type Foo struct{}
+type Bar struct{}
+func NewBar() *Bar { return &Bar{} }
+func (b *Bar) M() {}
+`
+	const expected = `package main
+
+const (
+	X = 1
+)
+
+const Y = 2
+
+type Bar struct{}
+
+func NewBar() *Bar { return &Bar{} }
+
+func (b *Bar) M() {}
+
+type Foo struct{}
+
+func a() {}
+
+func b() {}
+`
+	content, err := ReorderSource(ReorderConfig{
+		Filename:       "foo.go",
+		FormatCommand:  "gofmt",
+		Src:            []byte(source),
+		ReorderStructs: true,
+		Spacing:        SpacingOne,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s\n", expected, content)
+	}
+
+	_, err = ReorderSource(ReorderConfig{
+		Filename:      "foo.go",
+		FormatCommand: "gofmt",
+		Src:           []byte(source),
+		Spacing:       "two",
+	})
+	if err == nil {
+		t.Error("an error should occur with an invalid spacing")
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	// parse errors have the position of the first error
+	_, err := ReorderSource(ReorderConfig{
+		Filename:      "foo.go",
+		FormatCommand: "gofmt",
+		Src:           []byte("package main\n\nfunc a( {}\n"),
+	})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError, got %v", err)
+	}
+	if parseErr.Pos.Filename != "foo.go" || parseErr.Pos.Line != 3 || parseErr.Pos.Column != 9 {
+		t.Errorf("Unexpected position %s", parseErr.Pos)
+	}
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		t.Error("The ParseError should wrap the scanner errors")
+	}
+
+	// the pre-parse steps return the same error
+	_, err = ReorderSource(ReorderConfig{
+		Filename:         "foo.go",
+		FormatCommand:    "gofmt",
+		Src:              []byte("package main\n\nfunc a( {}\n"),
+		SortStructFields: true,
+		AlignStructs:     true,
+	})
+	if !errors.As(err, &parseErr) || parseErr.Pos.String() != "foo.go:3:9" {
+		t.Errorf("Expected a ParseError at foo.go:3:9, got %v", err)
+	}
+
+	_, err = ReorderSource(ReorderConfig{
+		Filename:      "foo.go",
+		FormatCommand: "wthcommand",
+		Src:           []byte("package main\n\nfunc b() {}\n\nfunc a() {}\n"),
+	})
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) || formatErr.Command != "wthcommand" {
+		t.Errorf("Expected a FormatError, got %v", err)
+	}
+	if !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("The FormatError should wrap the cause, got %v", err)
+	}
+}
+
+func TestVerifyOutput(t *testing.T) {
+	const source = `package main
+
+type T struct{}
+
+func (T) String() string { return "" }
+
+func init() {}
+
+func init() {}
+
+var _, a = 1, 2
+`
+	if err := verifyOutput("foo.go", []byte(source), []byte(source)); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	changed := strings.Replace(source, "func init() {}\n\nfunc init() {}", "func init() {}\n\nfunc b() {}", 1)
+	err := verifyOutput("foo.go", []byte(source), []byte(changed))
+	var verificationErr *VerificationError
+	if !errors.As(err, &verificationErr) {
+		t.Fatalf("Expected a VerificationError, got %v", err)
+	}
+	if strings.Join(verificationErr.Missing, ",") != "func init" || strings.Join(verificationErr.Unexpected, ",") != "func b" {
+		t.Errorf("Unexpected missing %v and unexpected %v", verificationErr.Missing, verificationErr.Unexpected)
+	}
+
+	err = verifyOutput("foo.go", []byte(source), []byte(source+"}"))
+	if !errors.As(err, &verificationErr) || !errors.As(err, new(*ParseError)) {
+		t.Errorf("Expected a VerificationError wrapping a ParseError, got %v", err)
+	}
+}
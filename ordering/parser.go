@@ -9,6 +9,13 @@ import (
 	"strings"
 )
 
+var basicTypes = map[string]bool{
+	"bool": true, "string": true, "byte": true, "rune": true, "uintptr": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true, "complex64": true, "complex128": true,
+}
+
 // Parse the given file and return the methods, constructors and structs.
 func Parse(filename string, src interface{}) (*ParsedInfo, error) {
 	fset := token.NewFileSet()
@@ -108,6 +115,143 @@ func GetTypeComments(d *ast.GenDecl) (comments []string) {
 	return
 }
 
+// associatedType returns the type name of the values declared in a const or var declaration,
+// when they are typed enough to be associated with this type. It follows the go/doc rules:
+// all typed specs (or untyped const specs repeating the previous type) must have the same
+// local type, and they must be at least 75% of the specs.
+func associatedType(d *ast.GenDecl) string {
+	domName := ""
+	domFreq := 0
+	prev := ""
+	for _, spec := range d.Specs {
+		s, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		name := ""
+		switch {
+		case s.Type != nil:
+			name = baseTypeName(s.Type)
+		case d.Tok == token.CONST && len(s.Values) == 0:
+			// iota continuation, the type is the previous one
+			name = prev
+		}
+		if name != "" {
+			if domName != "" && domName != name {
+				return ""
+			}
+			domName = name
+			domFreq++
+		}
+		prev = name
+	}
+	if domName == "" || domFreq < len(d.Specs)*3/4 {
+		return ""
+	}
+	return domName
+}
+
+// assertedType returns the type name T of a var declaration that only contains interface
+// assertions on T, as "var _ I = (*T)(nil)", "var _ I = T{}", "var _ I = &T{}" or
+// "var _ I = new(T)".
+func assertedType(d *ast.GenDecl) string {
+	if d.Tok != token.VAR {
+		return ""
+	}
+	asserted := ""
+	for _, spec := range d.Specs {
+		s, ok := spec.(*ast.ValueSpec)
+		if !ok || s.Type == nil || len(s.Values) != len(s.Names) {
+			return ""
+		}
+		for i, name := range s.Names {
+			if name.Name != "_" {
+				return ""
+			}
+			typename := assertionValueType(s.Values[i])
+			if typename == "" || (asserted != "" && asserted != typename) {
+				return ""
+			}
+			asserted = typename
+		}
+	}
+	return asserted
+}
+
+// assertionValueType returns the local type name of the value used in an interface assertion.
+func assertionValueType(expr ast.Expr) string {
+	switch v := expr.(type) {
+	case *ast.CallExpr:
+		// new(T)
+		if ident, ok := v.Fun.(*ast.Ident); ok && ident.Name == "new" && len(v.Args) == 1 {
+			return baseTypeName(v.Args[0])
+		}
+		// (*T)(nil) or T(value)
+		return baseTypeName(v.Fun)
+	case *ast.CompositeLit:
+		// T{}
+		return baseTypeName(v.Type)
+	case *ast.UnaryExpr:
+		// &T{}
+		if v.Op == token.AND {
+			return assertionValueType(v.X)
+		}
+	}
+	return ""
+}
+
+// baseTypeName returns the name of a local type, dereferencing pointers. Imported and
+// composite types return an empty string.
+func baseTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return baseTypeName(t.X)
+	case *ast.ParenExpr:
+		return baseTypeName(t.X)
+	}
+	return ""
+}
+
+// findCalls returns the identifiers used in the function body, and the names selected on the
+// receiver for methods, in order of first appearance.
+func findCalls(d *ast.FuncDecl) (calls, methodCalls []string) {
+	if d.Body == nil {
+		return
+	}
+	receiver := ""
+	if d.Recv != nil && len(d.Recv.List) > 0 && len(d.Recv.List[0].Names) > 0 {
+		receiver = d.Recv.List[0].Names[0].Name
+	}
+	seen := map[string]bool{}
+	seenMethods := map[string]bool{}
+
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok && receiver != "" && x.Name == receiver {
+				if !seenMethods[n.Sel.Name] {
+					seenMethods[n.Sel.Name] = true
+					methodCalls = append(methodCalls, n.Sel.Name)
+				}
+			}
+			// the selected name is not a top-level identifier
+			ast.Inspect(n.X, visit)
+			return false
+		case *ast.Ident:
+			if !seen[n.Name] {
+				seen[n.Name] = true
+				calls = append(calls, n.Name)
+			}
+		}
+		return true
+	}
+	ast.Inspect(d.Body, visit)
+	return
+}
+
 func findConstructors(d *ast.FuncDecl, fset *token.FileSet, sourceLines []string, constructors map[string][]*GoType) {
 
 	if d.Type == nil || d.Type.Results == nil || len(d.Type.Results.List) == 0 { // no return type
@@ -142,6 +286,7 @@ func findConstructors(d *ast.FuncDecl, fset *token.FileSet, sourceLines []string
 			OpeningLine: fset.Position(d.Pos()).Line,
 			ClosingLine: fset.Position(d.End()).Line,
 		}
+		method.Calls, method.MethodCalls = findCalls(d)
 		// Get the method source code
 		comments := GetMethodComments(d)
 		if len(comments) > 0 {
@@ -173,6 +318,10 @@ func findFunctions(d *ast.FuncDecl, fset *token.FileSet, sourceLines []string, f
 		OpeningLine: fset.Position(d.Pos()).Line,
 		ClosingLine: fset.Position(d.End()).Line,
 	}
+	functions[d.Name.Name].Calls, _ = findCalls(d)
+	if params := d.Type.Params; params != nil && len(params.List) > 0 {
+		functions[d.Name.Name].FirstParamType = baseTypeName(params.List[0].Type)
+	}
 	comments := GetMethodComments(d)
 	if len(comments) > 0 {
 		functions[d.Name.Name].SourceCode = strings.Join(comments, "\n") + "\n"
@@ -203,12 +352,13 @@ func findInterfaces(d *ast.GenDecl, fset *token.FileSet, sourceLines []string, i
 	}
 	for _, spec := range d.Specs {
 		if s, ok := spec.(*ast.TypeSpec); ok {
-			if _, ok := s.Type.(*ast.InterfaceType); ok {
+			if it, ok := s.Type.(*ast.InterfaceType); ok {
 				interfaceDef := &GoType{
 					Name:        s.Name.Name,
 					OpeningLine: fset.Position(d.Pos()).Line,
 					ClosingLine: fset.Position(d.End()).Line,
 				}
+				interfaceDef.InterfaceMethods, interfaceDef.InterfaceEmbeds = interfaceMembers(it)
 				comments := GetTypeComments(d)
 				if len(comments) > 0 {
 					interfaceDef.SourceCode = strings.Join(comments, "\n") + "\n"
@@ -222,6 +372,31 @@ func findInterfaces(d *ast.GenDecl, fset *token.FileSet, sourceLines []string, i
 	}
 }
 
+// interfaceMembers returns the method names and the embedded interfaces of an interface
+// type. Type constraints (unions, ~T) are ignored.
+func interfaceMembers(it *ast.InterfaceType) (methods, embeds []string) {
+	if it.Methods == nil {
+		return
+	}
+	for _, field := range it.Methods.List {
+		if len(field.Names) > 0 {
+			for _, name := range field.Names {
+				methods = append(methods, name.Name)
+			}
+			continue
+		}
+		switch t := field.Type.(type) {
+		case *ast.Ident:
+			embeds = append(embeds, t.Name)
+		case *ast.SelectorExpr:
+			if pkg, ok := t.X.(*ast.Ident); ok {
+				embeds = append(embeds, pkg.Name+"."+t.Sel.Name)
+			}
+		}
+	}
+	return
+}
+
 func findMethods(d *ast.FuncDecl, fset *token.FileSet, sourceLines []string, methods map[string][]*GoType) {
 
 	if d.Recv == nil {
@@ -251,6 +426,8 @@ func findMethods(d *ast.FuncDecl, fset *token.FileSet, sourceLines []string, met
 		OpeningLine: fset.Position(d.Pos()).Line,
 		ClosingLine: fset.Position(d.End()).Line,
 	}
+	_, method.PointerReceiver = recv.(*ast.StarExpr)
+	method.Calls, method.MethodCalls = findCalls(d)
 	comments := GetMethodComments(d)
 	if len(comments) > 0 {
 		method.SourceCode = strings.Join(comments, "\n") + "\n"
@@ -274,6 +451,7 @@ func findTypes(d *ast.GenDecl, fset *token.FileSet, sourceLines []string, typeNa
 				Name:        s.Name.Name,
 				OpeningLine: fset.Position(d.Pos()).Line,
 				ClosingLine: fset.Position(d.End()).Line,
+				Kind:        typeKind(s),
 			}
 			comments := GetTypeComments(d)
 			if len(comments) > 0 {
@@ -287,13 +465,33 @@ func findTypes(d *ast.GenDecl, fset *token.FileSet, sourceLines []string, typeNa
 	}
 }
 
+// typeKind returns the finer kind of the type declaration, from its underlying expression.
+func typeKind(s *ast.TypeSpec) Order {
+	if s.Assign.IsValid() {
+		return Alias
+	}
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		return Struct
+	case *ast.FuncType:
+		return FuncType
+	case *ast.Ident:
+		if basicTypes[t.Name] {
+			return Basic
+		}
+	}
+	return Type
+}
+
 func parseConstantAndVars(name *ast.Ident, d *ast.GenDecl, fset *token.FileSet, sourceLines []string, varTypes, constTypes map[string]*GoType) {
 
 	// log the source code for the variable or constant
 	varDef := &GoType{
-		Name:        name.Name,
-		OpeningLine: fset.Position(d.Pos()).Line,
-		ClosingLine: fset.Position(d.End()).Line,
+		Name:           name.Name,
+		OpeningLine:    fset.Position(d.Pos()).Line,
+		ClosingLine:    fset.Position(d.End()).Line,
+		AssociatedType: associatedType(d),
+		AssertedType:   assertedType(d),
 	}
 	comments := GetTypeComments(d)
 	if len(comments) > 0 {
@@ -310,6 +508,9 @@ func parseConstantAndVars(name *ast.Ident, d *ast.GenDecl, fset *token.FileSet,
 	if _, ok := varTypes[signature]; ok {
 		return
 	}
+	if _, ok := constTypes[signature]; ok {
+		return
+	}
 
 	switch d.Tok {
 	case token.CONST:
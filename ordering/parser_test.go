@@ -115,3 +115,39 @@ func TestParseReturnNoPointer(t *testing.T) {
 		t.Errorf("Expected 1 method, got %d", len(parsed.Methods))
 	}
 }
+
+func TestAssociatedType(t *testing.T) {
+	const source = `package main
+
+const (
+	A Color = iota
+	B
+)
+
+const (
+	C Color = 1
+	D Size  = 2
+)
+
+var E *Color
+
+var F time.Duration
+
+const G = 1
+`
+	parsed, err := Parse("test.go", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"A": "Color", "C": "", "E": "Color", "F": "", "G": ""}
+	for _, decl := range parsed.Constants {
+		if expected[decl.Name] != decl.AssociatedType {
+			t.Errorf("%s should be associated to %q, got %q", decl.Name, expected[decl.Name], decl.AssociatedType)
+		}
+	}
+	for _, decl := range parsed.Variables {
+		if expected[decl.Name] != decl.AssociatedType {
+			t.Errorf("%s should be associated to %q, got %q", decl.Name, expected[decl.Name], decl.AssociatedType)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package ordering
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// PatternKinds are the kinds that accept a name pattern in the order list, e.g. "type:*Error".
+var PatternKinds = append([]Order{Const, Var, Interface, Type, Func}, TypeKinds...)
+
+// orderRule is an entry of the order list: a kind, with an optional name pattern.
+type orderRule struct {
+	kind    Order
+	pattern string
+	match   func(name string) bool
+}
+
+// accepts returns true if the rule can claim a declaration of the given kind. The "type"
+// kind accepts all types, whatever their finer kind.
+func (r *orderRule) accepts(kind Order) bool {
+	if r.kind == Type {
+		return kind == Type || inList(kind, TypeKinds)
+	}
+	return r.kind == kind
+}
+
+// ParseOrder parses an entry of the order list. An entry is a kind ("type"), or a kind
+// followed by a name pattern ("type:*Error"). The pattern is a glob (path.Match syntax) or
+// a regular expression between slashes ("func:/^Must[A-Z]/").
+func ParseOrder(entry Order) (kind Order, pattern string, err error) {
+	_, err = parseOrderRule(entry)
+	if err != nil {
+		return "", "", err
+	}
+	kind, pattern, _ = strings.Cut(entry, ":")
+	return kind, pattern, nil
+}
+
+// claimDeclarations returns, for each declaration matched by a pattern rule, the index of
+// the first matching rule in the order list.
+func claimDeclarations(info *ParsedInfo, rules []*orderRule) map[*GoType]int {
+	claims := make(map[*GoType]int)
+	claim := func(decl *GoType, kind Order) {
+		if _, ok := claims[decl]; ok {
+			return
+		}
+		for i, rule := range rules {
+			if rule.match == nil || !rule.accepts(kind) {
+				continue
+			}
+			if rule.match(decl.Name) {
+				claims[decl] = i
+				return
+			}
+		}
+	}
+	for _, decl := range info.Constants {
+		claim(decl, Const)
+	}
+	for _, decl := range info.Variables {
+		claim(decl, Var)
+	}
+	for _, decl := range info.Interfaces {
+		claim(decl, Interface)
+	}
+	for _, decl := range info.Types {
+		claim(decl, decl.Kind)
+	}
+	for name, decl := range info.Functions {
+		if name == "init" || name == "main" {
+			continue
+		}
+		claim(decl, Func)
+	}
+	return claims
+}
+
+// claimedNames returns the names (keys of decls) claimed by the rule at the given index. A
+// negative index returns the names that are not claimed by any pattern rule.
+func claimedNames(names []string, decls map[string]*GoType, claims map[*GoType]int, rule int) []string {
+	selected := []string{}
+	for _, name := range names {
+		index, ok := claims[decls[name]]
+		if (rule < 0 && !ok) || (ok && index == rule) {
+			selected = append(selected, name)
+		}
+	}
+	return selected
+}
+
+// parseOrderRule parses an entry of the order list.
+func parseOrderRule(entry Order) (*orderRule, error) {
+	kind, pattern, found := strings.Cut(entry, ":")
+	rule := &orderRule{kind: kind, pattern: pattern}
+	if !found {
+		return rule, nil
+	}
+	if !inList(kind, PatternKinds) {
+		return nil, fmt.Errorf("invalid order %q: %q does not accept a name pattern", entry, kind)
+	}
+	if pattern == "" {
+		return nil, fmt.Errorf("invalid order %q: empty name pattern", entry)
+	}
+
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid order %q: %w", entry, err)
+		}
+		rule.match = re.MatchString
+		return rule, nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid order %q: %w", entry, err)
+	}
+	rule.match = func(name string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}
+	return rule, nil
+}
+
+// parseOrderRules parses the order list.
+func parseOrderRules(defOrder []Order) ([]*orderRule, error) {
+	rules := make([]*orderRule, len(defOrder))
+	for i, entry := range defOrder {
+		rule, err := parseOrderRule(entry)
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = rule
+	}
+	return rules, nil
+}
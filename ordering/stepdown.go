@@ -0,0 +1,73 @@
+package ordering
+
+import "go/token"
+
+// stepdownMethods orders the methods of each type with stepdownOrder, following the calls
+// made on the receiver.
+func stepdownMethods(methods map[string][]*GoType) {
+	for typename, list := range methods {
+		byName := make(map[string]*GoType, len(list))
+		names := make([]string, len(list))
+		for i, method := range list {
+			byName[method.Name] = method
+			names[i] = method.Name
+		}
+		names = stepdownOrder(names, func(name string) []string {
+			return byName[name].MethodCalls
+		})
+		for i, name := range names {
+			methods[typename][i] = byName[name]
+		}
+	}
+}
+
+// stepdownOrder returns the names ordered so that each one is followed by the names it calls,
+// depth-first and in order of first call ("newspaper" rule). The walk starts from the
+// exported names, then from the names that are not called by the others, then from the
+// remaining ones (cycles), each time in the given order. So the result is deterministic if
+// the given names are sorted.
+func stepdownOrder(names []string, calls func(name string) []string) []string {
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+	called := map[string]bool{}
+	for _, name := range names {
+		for _, callee := range calls(name) {
+			if known[callee] && callee != name {
+				called[callee] = true
+			}
+		}
+	}
+
+	result := make([]string, 0, len(names))
+	visited := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		result = append(result, name)
+		for _, callee := range calls(name) {
+			if known[callee] {
+				visit(callee)
+			}
+		}
+	}
+
+	for _, name := range names {
+		if token.IsExported(name) {
+			visit(name)
+		}
+	}
+	for _, name := range names {
+		if !called[name] {
+			visit(name)
+		}
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return result
+}
@@ -8,8 +8,58 @@ const (
 	Interface Order = "interface"
 	Type      Order = "type"
 	Func      Order = "func"
+	Struct    Order = "struct"   // type T struct{...}
+	Alias     Order = "alias"    // type T = U
+	FuncType  Order = "functype" // type T func(...)
+	Basic     Order = "basic"    // named basic type, e.g. type T int
 )
 
+// Placement of the interface assertions, e.g. var _ I = (*T)(nil)
+const (
+	AssertionsInVars        = "var"            // in the var section, as other vars
+	AssertionsAfterType     = "after-type"     // right after the asserted type declaration
+	AssertionsBeforeMethods = "before-methods" // after the constructors of the asserted type
+)
+
+// AssertionPlacements are the allowed placements of the interface assertions.
+var AssertionPlacements = []string{AssertionsInVars, AssertionsAfterType, AssertionsBeforeMethods}
+
+// Keys of the method sub-ordering, they are applied in the given order, the name being
+// always the last resort.
+const (
+	MethodExported  = "exported"  // exported methods first
+	MethodReceiver  = "receiver"  // value receivers before pointer receivers
+	MethodWellKnown = "wellknown" // WellKnownMethods last, in this order
+	MethodName      = "name"      // alphabetical order
+)
+
+// MethodOrderKeys are the allowed keys of the method sub-ordering.
+var MethodOrderKeys = []string{MethodExported, MethodReceiver, MethodWellKnown, MethodName}
+
+// WellKnownMethods are the methods that the "wellknown" key places at the end of the
+// methods, in this order.
+var WellKnownMethods = []string{
+	"String", "GoString", "Format", "Error", "Unwrap",
+	"MarshalJSON", "UnmarshalJSON", "MarshalText", "UnmarshalText", "MarshalBinary", "UnmarshalBinary",
+	"Close",
+}
+
+// Spacing policies between the moved declarations.
+const (
+	SpacingPreserve = "preserve" // as in the source
+	SpacingOne      = "one"      // exactly one blank line
+)
+
+// SpacingPolicies are the allowed spacing policies.
+var SpacingPolicies = []string{SpacingPreserve, SpacingOne}
+
+// TypeKinds are the finer kinds of types. If they are not in the order list, the
+// corresponding types are placed with the "type" kind.
+var TypeKinds = []Order{Struct, Alias, FuncType, Basic}
+
+// PriorityKinds are the kinds that accept a priority list.
+var PriorityKinds = []Order{Type, Interface, Func}
+
 // GoType represents a struct, method or constructor. The "SourceCode" field contains the doc comment and source in Go, formated and ready to be injected in the source file.
 type GoType struct {
 	// Name of the struct, method or constructor
@@ -23,6 +73,38 @@ type GoType struct {
 
 	// ClosingLine is the line number where the struct, method or constructor ends in the source file.
 	ClosingLine int
+
+	// Kind is the finer kind of a type declaration (struct, alias, functype, basic or type).
+	// It is empty for other declarations.
+	Kind Order
+
+	// AssociatedType is, for const and var declarations, the name of the type of the values
+	// when "go doc" would associate them to this type. It is empty otherwise.
+	AssociatedType string
+
+	// AssertedType is, for var declarations that only contain compile-time interface
+	// assertions (var _ I = (*T)(nil)), the name of the asserted type T. It is empty otherwise.
+	AssertedType string
+
+	// Calls contains, for functions and methods, the identifiers used in the body (called
+	// functions, function values...) in order of first appearance.
+	Calls []string
+
+	// MethodCalls contains, for methods, the methods and fields selected on the receiver
+	// (r.Method()) in order of first appearance.
+	MethodCalls []string
+
+	// FirstParamType is, for functions, the local type name of the first parameter (T for
+	// "func f(t *T)"). It is empty otherwise.
+	FirstParamType string
+
+	// InterfaceMethods contains, for interfaces, the names of the declared methods in
+	// declaration order, and InterfaceEmbeds the embedded interfaces ("Base", "io.Reader").
+	InterfaceMethods []string
+	InterfaceEmbeds  []string
+
+	// PointerReceiver is true for the methods declared on a pointer receiver.
+	PointerReceiver bool
 }
 
 // Order is the type of order, it's an alias of string.
@@ -49,4 +131,59 @@ type ReorderConfig struct {
 	DefOrder       []Order
 	ReorderStructs bool
 	Diff           bool
+
+	// Associate places the consts and vars of a type right after the type declaration,
+	// before its constructors and methods, as "go doc" groups them.
+	Associate bool
+
+	// Assertions is the placement of the interface assertions (var _ I = (*T)(nil)), see
+	// AssertionPlacements. Empty means in the var section.
+	Assertions string
+
+	// Stepdown orders the functions, and the methods of each type, so that a function is
+	// followed by the functions it calls (depth-first), starting from the exported ones.
+	Stepdown bool
+
+	// ColocateHelpers places the unexported functions that are only used by the methods and
+	// constructors of one type, or that take this type as first parameter, after its methods.
+	ColocateHelpers bool
+
+	// GroupByInterface groups the methods of a type that satisfy an interface declared in the
+	// file, in Interfaces or in KnownInterfaces, in the interface declaration order.
+	GroupByInterface bool
+
+	// Interfaces are the method sets declared in the other files of the package.
+	Interfaces []MethodSet
+
+	// MethodOrder contains the keys used to sort the methods of a type (MethodOrderKeys),
+	// methods are sorted by name if it is empty.
+	MethodOrder []string
+
+	// PairAccessors keeps the getters and setters (Name/SetName, IsEnabled/SetEnabled)
+	// together, getter first, the pairs being sorted by property name.
+	PairAccessors bool
+
+	// SortStructFields sorts the fields of the struct types, and SortInterfaceMethods the
+	// methods of the interfaces, in each group of lines, embedded ones first.
+	SortStructFields     bool
+	SortInterfaceMethods bool
+
+	// AlignStructs reorders the fields of the structs to minimize the padding, with the sizes
+	// of AlignArch (DefaultAlignArch if empty).
+	AlignStructs bool
+	AlignArch    string
+
+	// Banners inserts a banner comment (e.g. "// Types") before each group of declarations,
+	// with BannerTemplate (DefaultBannerTemplate if empty). The banners written by a previous
+	// run are replaced.
+	Banners        bool
+	BannerTemplate string
+
+	// Spacing is the spacing policy between the moved declarations (SpacingPolicies), the
+	// spacing of the source is preserved if it is empty.
+	Spacing string
+
+	// Priority contains, per kind (type, interface or func), the names to place first in
+	// the given order. The other names are sorted as usual.
+	Priority map[Order][]string
 }
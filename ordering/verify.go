@@ -0,0 +1,73 @@
+package ordering
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+)
+
+// verifyOutput checks that the reordered source is valid and declares the same elements as
+// the source, so a bug in the reordering cannot lose or duplicate a declaration.
+func verifyOutput(filename string, before, after []byte) error {
+	expected, err := declarationCounts(filename, before)
+	if err != nil {
+		return newParseError(filename, err)
+	}
+	found, err := declarationCounts(filename, after)
+	if err != nil {
+		return &VerificationError{Err: newParseError(filename, err)}
+	}
+
+	e := &VerificationError{}
+	for decl, count := range expected {
+		for i := found[decl]; i < count; i++ {
+			e.Missing = append(e.Missing, decl)
+		}
+	}
+	for decl, count := range found {
+		for i := expected[decl]; i < count; i++ {
+			e.Unexpected = append(e.Unexpected, decl)
+		}
+	}
+	if len(e.Missing) == 0 && len(e.Unexpected) == 0 {
+		return nil
+	}
+	sort.Strings(e.Missing)
+	sort.Strings(e.Unexpected)
+	return e
+}
+
+// declarationCounts returns the top level declarations of the source, e.g. "type T" or
+// "func (T) String", with the number of times they are declared (init, or the blank
+// identifier, can be declared several times). The imports are ignored, goimports can change
+// them.
+func declarationCounts(filename string, src []byte) (map[string]int, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				counts["func ("+embeddedName(d.Recv.List[0].Type)+") "+d.Name.Name]++
+			} else {
+				counts["func "+d.Name.Name]++
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					counts["type "+s.Name.Name]++
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						counts[d.Tok.String()+" "+name.Name]++
+					}
+				}
+			}
+		}
+	}
+	return counts, nil
+}